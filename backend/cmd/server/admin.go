@@ -0,0 +1,525 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"winterm-bridge/internal/auth"
+	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/llm"
+	"winterm-bridge/internal/monitor"
+	"winterm-bridge/internal/notify"
+	"winterm-bridge/internal/tmux"
+)
+
+// subcommands are admin commands run instead of the server; each returns the exit code
+var subcommands = map[string]func(args []string) int{
+	"status":       cmdStatus,
+	"stop":         cmdStop,
+	"restart":      cmdRestart,
+	"reset-pin":    cmdResetPIN,
+	"print-config": cmdPrintConfig,
+	"config":       cmdConfig,
+	"doctor":       cmdDoctor,
+}
+
+const subcommandUsage = `Usage: winterm-bridge [flags]                Run the server
+       winterm-bridge status                Show whether the server is running
+       winterm-bridge stop                  Stop the running server
+       winterm-bridge restart               Restart in place without dropping the port or terminals
+       winterm-bridge reset-pin [PIN]       Set a new PIN (random if omitted)
+       winterm-bridge print-config          Print runtime.json with secrets masked
+       winterm-bridge config validate [FILE] Check runtime.json (or FILE) for invalid and unknown fields
+       winterm-bridge config rotate-key     Re-encrypt the secrets in runtime.json with a new key
+       winterm-bridge doctor                Check tmux, config, port and LLM/SMTP reachability
+`
+
+// serverPID returns the recorded PID if that process is still alive, or 0
+func serverPID(cfg *config.Config) int {
+	if cfg.PID <= 0 {
+		return 0
+	}
+	err := syscall.Kill(cfg.PID, 0)
+	if err == nil || errors.Is(err, syscall.EPERM) {
+		return cfg.PID
+	}
+	return 0
+}
+
+func cmdStatus(args []string) int {
+	cfg, err := config.LoadSealed()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	pid := serverPID(cfg)
+	if pid == 0 {
+		fmt.Println("Server:    not running")
+	} else {
+		fmt.Printf("Server:    running (pid %d)\n", pid)
+	}
+	fmt.Printf("Port:      %s", cfg.Port)
+	if pid != 0 {
+		if err := checkHTTP(cfg.Port); err != nil {
+			fmt.Printf(" (not responding: %v)", err)
+		}
+	}
+	fmt.Println()
+	fmt.Printf("Config:    %s\n", config.ConfigPath())
+
+	sessions, _ := tmux.ListSessions()
+	fmt.Printf("Sessions:  %d tmux, %d persistent\n", len(sessions), len(cfg.PersistentSessions))
+	for _, name := range sessions {
+		fmt.Printf("           %s\n", strings.TrimPrefix(name, tmux.SessionPrefix))
+	}
+
+	if ai := cfg.AIMonitor; ai != nil && ai.Enabled {
+		fmt.Printf("Monitor:   enabled (%s, every %ds)\n", ai.Model, ai.Interval)
+	} else {
+		fmt.Println("Monitor:   disabled")
+	}
+	if e := cfg.Email; e != nil && e.Enabled {
+		fmt.Printf("Email:     enabled (%s)\n", e.SMTPHost)
+	} else {
+		fmt.Println("Email:     disabled")
+	}
+	enabled := 0
+	for _, ch := range cfg.NotifyChannels {
+		if ch.Enabled {
+			enabled++
+		}
+	}
+	fmt.Printf("Channels:  %d enabled\n", enabled)
+
+	if pid == 0 {
+		return 3 // LSB status code for "not running"
+	}
+	return 0
+}
+
+func cmdStop(args []string) int {
+	cfg, err := config.LoadSealed()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+	pid := serverPID(cfg)
+	if pid == 0 {
+		fmt.Println("Server is not running")
+		return 0
+	}
+
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to signal pid %d: %v\n", pid, err)
+		return 1
+	}
+	// The server drains connections for up to shutdownTimeout before exiting
+	deadline := time.Now().Add(shutdownTimeout + 5*time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		if syscall.Kill(pid, 0) != nil {
+			fmt.Printf("Stopped server (pid %d)\n", pid)
+			return 0
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Server (pid %d) did not exit within %s\n", pid, shutdownTimeout+5*time.Second)
+	return 1
+}
+
+// cmdRestart asks the server to hand its socket to a fresh copy of the (possibly upgraded)
+// binary, then waits for the new process to record its PID
+func cmdRestart(args []string) int {
+	cfg, err := config.LoadSealed()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+	pid := serverPID(cfg)
+	if pid == 0 {
+		fmt.Fprintln(os.Stderr, "Server is not running")
+		return 3
+	}
+
+	if err := syscall.Kill(pid, syscall.SIGUSR2); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to signal pid %d: %v\n", pid, err)
+		return 1
+	}
+	deadline := time.Now().Add(handoffTimeout + 5*time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(200 * time.Millisecond)
+		cfg, err := config.LoadSealed()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+			return 1
+		}
+		if newPID := serverPID(cfg); newPID != 0 && newPID != pid {
+			fmt.Printf("Restarted server (pid %d -> %d)\n", pid, newPID)
+			return 0
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Server (pid %d) did not hand over within %s; check its log\n", pid, handoffTimeout+5*time.Second)
+	return 1
+}
+
+func cmdResetPIN(args []string) int {
+	pin := ""
+	if len(args) > 0 {
+		pin = args[0]
+		if len(pin) < 4 {
+			fmt.Fprintln(os.Stderr, "PIN must be at least 4 characters")
+			return 2
+		}
+	} else {
+		pin = auth.GeneratePIN()
+	}
+
+	// Encrypting the new PIN needs the server's key, so set WINTERM_CONFIG_KEY or
+	// WINTERM_CONFIG_KEY_COMMAND as the server does
+	if err := config.Update(func(c *config.Config) error {
+		c.PIN = pin
+		return nil
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save config: %v\n", err)
+		return 1
+	}
+	cfg, err := config.LoadSealed()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("New PIN: %s\n", pin)
+	if os.Getenv("WINTERM_PIN") != "" {
+		fmt.Println("Note: WINTERM_PIN is set and takes precedence over the saved PIN")
+	}
+	if pid := serverPID(cfg); pid != 0 {
+		// SIGHUP makes the running server reload runtime.json
+		if err := syscall.Kill(pid, syscall.SIGHUP); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to signal pid %d: %v\nRestart the server to apply it\n", pid, err)
+			return 1
+		}
+		fmt.Printf("Server (pid %d) reloaded\n", pid)
+	}
+	return 0
+}
+
+// secretKeys are JSON fields masked by print-config
+var secretKeys = map[string]bool{
+	"pin": true, "api_key": true, "password": true, "secret": true, "token": true,
+	"vapid_private_key": true, "p256dh": true, "auth": true, "metrics_token": true,
+}
+
+func cmdPrintConfig(args []string) int {
+	data, err := os.ReadFile(config.ConfigPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Println("{}")
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "Failed to read config: %v\n", err)
+		return 1
+	}
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid config: %v\n", err)
+		return 1
+	}
+	out, _ := json.MarshalIndent(maskSecrets(raw), "", "  ")
+	fmt.Println(string(out))
+	return 0
+}
+
+// maskSecrets replaces non-empty secret values anywhere in a decoded JSON document
+func maskSecrets(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if s, ok := val.(string); ok && secretKeys[k] && s != "" {
+				t[k] = "****"
+			} else if h, ok := val.(map[string]any); ok && k == "headers" {
+				// Custom webhook headers usually carry credentials
+				for name := range h {
+					h[name] = "****"
+				}
+			} else {
+				t[k] = maskSecrets(val)
+			}
+		}
+	case []any:
+		for i := range t {
+			t[i] = maskSecrets(t[i])
+		}
+	}
+	return v
+}
+
+func cmdConfig(args []string) int {
+	switch {
+	case len(args) >= 1 && len(args) <= 2 && args[0] == "validate":
+		return cmdConfigValidate(args[1:])
+	case len(args) == 1 && args[0] == "rotate-key":
+		return cmdRotateKey()
+	}
+	fmt.Fprintln(os.Stderr, "Usage: winterm-bridge config validate [FILE]\n       winterm-bridge config rotate-key")
+	return 2
+}
+
+func cmdRotateKey() int {
+	source, err := config.RotateKey()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to rotate key: %v\n", err)
+		return 1
+	}
+	if source == "file" {
+		fmt.Printf("Secrets re-encrypted with a new key in %s\n", config.KeyPath())
+		return 0
+	}
+	fmt.Println("Secrets re-encrypted with the first configured key; the old key can now be removed")
+	if cfg, err := config.LoadSealed(); err == nil && serverPID(cfg) != 0 {
+		fmt.Println("Restart the server with the new key so it can read runtime.json")
+	}
+	return 0
+}
+
+func cmdConfigValidate(args []string) int {
+	path := config.ConfigPath()
+	if len(args) == 1 {
+		path = args[0]
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read config: %v\n", err)
+		return 1
+	}
+	cfg, info, err := config.Parse(data)
+	if err != nil {
+		fmt.Printf("%s: %v\n", path, err)
+		return 1
+	}
+	if info.Version < config.CurrentVersion {
+		fmt.Printf("%s: version %d, upgraded to %d when the server next saves it\n", path, info.Version, config.CurrentVersion)
+	}
+	for _, field := range info.Unknown {
+		fmt.Printf("warning: %s: unknown field, dropped when the server next saves the file\n", field)
+	}
+	problems := configProblems(cfg)
+	for _, p := range problems {
+		fmt.Printf("error: %s\n", p)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%s: %d invalid value(s)\n", path, len(problems))
+		return 1
+	}
+	fmt.Printf("%s: OK\n", path)
+	return 0
+}
+
+// configProblems lists invalid values, including channel and rule settings checked by
+// packages the config package can't import
+func configProblems(cfg *config.Config) []string {
+	var out []string
+	if err := config.Validate(cfg); err != nil {
+		for _, f := range err.(*config.ValidationError).Fields {
+			out = append(out, f.Error())
+		}
+	}
+	for i, ch := range cfg.NotifyChannels {
+		if _, err := notify.Build(ch); err != nil {
+			out = append(out, fmt.Sprintf("notify_channels[%d]: %v", i, err))
+		}
+	}
+	if err := monitor.ValidateNotifyRules(cfg.NotifyRules); err != nil {
+		out = append(out, fmt.Sprintf("notify_rules: %v", err))
+	}
+	return out
+}
+
+// doctor records check results
+type doctor struct {
+	failed bool
+}
+
+func (d *doctor) ok(format string, a ...any)   { fmt.Printf("[ OK ] "+format+"\n", a...) }
+func (d *doctor) warn(format string, a ...any) { fmt.Printf("[WARN] "+format+"\n", a...) }
+func (d *doctor) fail(format string, a ...any) {
+	d.failed = true
+	fmt.Printf("[FAIL] "+format+"\n", a...)
+}
+
+func cmdDoctor(args []string) int {
+	d := &doctor{}
+	d.checkTmux()
+
+	cfg, err := config.LoadSealed()
+	decrypted := false
+	if err != nil {
+		d.fail("config %s: %v", config.ConfigPath(), err)
+		cfg = &config.Config{Port: "8080"}
+	} else {
+		// The key is often only in the server's environment; check the rest without it
+		if plain, err := config.Load(); err != nil {
+			d.warn("secrets: %v; run with the server's key to check the PIN and LLM", err)
+		} else {
+			cfg, decrypted = plain, true
+		}
+		d.checkConfig(cfg, decrypted)
+	}
+	d.checkPort(cfg)
+	if decrypted {
+		d.checkLLM(cfg.AIMonitor)
+	}
+	d.checkSMTP(cfg.Email)
+
+	if d.failed {
+		return 1
+	}
+	return 0
+}
+
+// minTmuxVersion is the oldest tmux known to work (control mode, window-size latest)
+const minTmuxVersion = 2.9
+
+func (d *doctor) checkTmux() {
+	version, err := tmux.CheckTmuxAvailable()
+	if err != nil {
+		d.fail("tmux: %v", err)
+		return
+	}
+	if v, ok := parseTmuxVersion(version); ok && v < minTmuxVersion {
+		d.warn("%s is older than %.1f; window sizing may misbehave", version, minTmuxVersion)
+	} else {
+		d.ok("%s", version)
+	}
+
+	socket := os.Getenv("WINTERM_TMUX_SOCKET")
+	if socket == "" {
+		socket = fmt.Sprintf("/tmp/tmux-%d/default", os.Getuid())
+	}
+	dir := filepath.Dir(socket)
+	info, err := os.Stat(dir)
+	switch {
+	case os.IsNotExist(err):
+		d.warn("tmux socket directory %s does not exist yet (no tmux server running)", dir)
+		return
+	case err != nil:
+		d.fail("tmux socket directory %s: %v", dir, err)
+		return
+	case info.Mode().Perm()&0077 != 0:
+		d.warn("tmux socket directory %s is accessible by other users (mode %o)", dir, info.Mode().Perm())
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		d.fail("tmux socket directory %s is owned by uid %d, not %d", dir, st.Uid, os.Getuid())
+		return
+	}
+	if _, err := os.Stat(socket); err != nil {
+		d.warn("tmux socket %s not found; set WINTERM_TMUX_SOCKET if tmux uses another socket", socket)
+		return
+	}
+	d.ok("tmux socket %s", socket)
+}
+
+// parseTmuxVersion extracts the numeric version from "tmux 3.3a"
+func parseTmuxVersion(s string) (float64, bool) {
+	m := regexp.MustCompile(`(\d+\.\d+)`).FindString(s)
+	v, err := strconv.ParseFloat(m, 64)
+	return v, err == nil
+}
+
+func (d *doctor) checkConfig(cfg *config.Config, decrypted bool) {
+	problems := configProblems(cfg)
+	for _, p := range problems {
+		d.fail("config: %s", p)
+	}
+	if decrypted && cfg.PIN != "" && len(cfg.PIN) < 4 {
+		d.fail("config: pin is shorter than 4 characters and will be replaced at startup")
+		problems = append(problems, "pin")
+	}
+	if ai := cfg.AIMonitor; ai != nil && ai.Enabled && (ai.Endpoint == "" || ai.APIKey == "" || ai.Model == "") {
+		d.fail("config: AI monitor is enabled but endpoint, api_key or model is missing")
+		problems = append(problems, "ai_monitor")
+	}
+	if len(problems) == 0 {
+		d.ok("config %s", config.ConfigPath())
+	}
+}
+
+func (d *doctor) checkPort(cfg *config.Config) {
+	if pid := serverPID(cfg); pid != 0 {
+		if err := checkHTTP(cfg.Port); err != nil {
+			d.fail("server (pid %d) is not answering on port %s: %v", pid, cfg.Port, err)
+		} else {
+			d.ok("server (pid %d) is answering on port %s", pid, cfg.Port)
+		}
+		return
+	}
+	ln, err := net.Listen("tcp", ":"+cfg.Port)
+	if err != nil {
+		d.fail("port %s is not available: %v", cfg.Port, err)
+		return
+	}
+	ln.Close()
+	d.ok("port %s is available", cfg.Port)
+}
+
+func (d *doctor) checkLLM(ai *config.AIMonitorConfig) {
+	if ai == nil || !ai.Enabled {
+		d.ok("AI monitor disabled; skipping LLM check")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	apiKey, err := config.ResolveSecret(ai.APIKey)
+	if err != nil {
+		d.fail("LLM api_key: %v", err)
+		return
+	}
+	provider := llm.NewOpenAICompatProvider(llm.Config{Endpoint: ai.Endpoint, APIKey: apiKey, Model: ai.Model})
+	if err := provider.TestConnection(ctx); err != nil {
+		d.fail("LLM %s (%s): %v", ai.Endpoint, ai.Model, err)
+		return
+	}
+	d.ok("LLM %s (%s) answered", ai.Endpoint, ai.Model)
+}
+
+func (d *doctor) checkSMTP(e *config.EmailConfig) {
+	if e == nil || !e.Enabled || e.SMTPHost == "" {
+		d.ok("email disabled; skipping SMTP check")
+		return
+	}
+	port := e.SMTPPort
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(e.SMTPHost, strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		d.fail("SMTP %s: %v", addr, err)
+		return
+	}
+	conn.Close()
+	d.ok("SMTP %s is reachable", addr)
+}
+
+// checkHTTP verifies that the local server answers HTTP requests
+func checkHTTP(port string) error {
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get("http://127.0.0.1:" + port + "/api/auth/validate")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/health"
+	"winterm-bridge/internal/monitor"
+	"winterm-bridge/internal/pty"
+)
+
+// monitorStallAfter is how long the monitor loop may go without ticking before /readyz fails
+const monitorStallAfter = 30 * time.Second
+
+// registerHealthChecks adds the /readyz dependency checks
+func registerHealthChecks(checker *health.Checker, ptyManager *pty.Manager, monitorService *monitor.Service, static fs.FS) {
+	checker.Add("tmux", func(ctx context.Context) (string, error) {
+		socket := ptyManager.SocketPath()
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "tmux", "-S", socket, "list-sessions", "-F", "#{session_name}")
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			msg := strings.TrimSpace(stderr.String())
+			// No server yet is fine: creating a session starts one
+			if strings.Contains(msg, "no server running") || strings.Contains(msg, "No such file or directory") {
+				return "no tmux server running on " + socket, nil
+			}
+			if msg == "" {
+				msg = err.Error()
+			}
+			return "", fmt.Errorf("%s: %s", socket, msg)
+		}
+		return fmt.Sprintf("%d session(s) on %s", strings.Count(string(out), "\n"), socket), nil
+	})
+
+	checker.Add("config_dir", func(ctx context.Context) (string, error) {
+		dir := config.DefaultConfigDir()
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", err
+		}
+		f, err := os.CreateTemp(dir, ".readyz-*")
+		if err != nil {
+			return "", err
+		}
+		f.Close()
+		os.Remove(f.Name())
+		return dir + " is writable", nil
+	})
+
+	checker.Add("static", func(ctx context.Context) (string, error) {
+		if _, err := fs.Stat(static, "index.html"); err != nil {
+			return "", fmt.Errorf("embedded frontend missing: %w", err)
+		}
+		return "index.html embedded", nil
+	})
+
+	checker.Add("monitor", func(ctx context.Context) (string, error) {
+		if !monitorService.IsRunning() {
+			return "disabled", nil
+		}
+		age := time.Since(monitorService.LastTick())
+		if age > monitorStallAfter {
+			return "", fmt.Errorf("scheduler has not ticked for %s", age.Round(time.Second))
+		}
+		return fmt.Sprintf("last tick %s ago", age.Round(time.Millisecond)), nil
+	})
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"winterm-bridge/internal/auth"
+	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/events"
+	"winterm-bridge/internal/health"
+	"winterm-bridge/internal/logging"
+	"winterm-bridge/internal/metrics"
+	"winterm-bridge/internal/monitor"
+	"winterm-bridge/internal/pty"
+	"winterm-bridge/internal/session"
+)
+
+// shutdownTimeout bounds the whole drain; whatever is still running afterwards is cut off
+const shutdownTimeout = 10 * time.Second
+
+// handoffTimeout bounds how long a restart waits for the new process to become ready
+const handoffTimeout = 30 * time.Second
+
+// handoffGrace lets connections accepted just before a handoff send their first request
+const handoffGrace = 500 * time.Millisecond
+
+// Environment variables passing the inherited listener and readiness pipe to a restarted process
+const (
+	listenFDEnv = "WINTERM_LISTEN_FD"
+	readyFDEnv  = "WINTERM_READY_FD"
+)
+
+// lifecycle handles process signals: SIGINT/SIGTERM drain and exit, SIGHUP rereads runtime.json,
+// SIGUSR2 restarts in place by handing the listening socket to a new process
+type lifecycle struct {
+	srv            *http.Server
+	ln             net.Listener
+	handingOff     atomic.Bool        // Set once the listener belongs to the new process
+	cancelBase     context.CancelFunc // Cancels every request context and background loop
+	eventHub       *events.Hub
+	ptyManager     *pty.Manager
+	monitorService *monitor.Service
+	registry       *session.Registry
+	done           chan struct{} // Closed once shutdown has finished
+}
+
+// run waits for signals until shutdown begins
+// A second SIGINT/SIGTERM during the drain exits immediately
+func (l *lifecycle) run() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR2)
+	for sig := range sigCh {
+		switch sig {
+		case syscall.SIGHUP:
+			l.reload()
+			continue
+		case syscall.SIGUSR2:
+			if err := l.handoff(); err != nil {
+				logger.Error("Restart failed, still serving", "err", err)
+				continue
+			}
+			logger.Info("Handing over to new process")
+		default:
+			logger.Info("Shutting down", "signal", sig.String())
+		}
+		go func() {
+			for sig := range sigCh {
+				if sig == syscall.SIGINT || sig == syscall.SIGTERM {
+					logger.Warn("Second signal, exiting immediately", "signal", sig.String())
+					config.ClearPID()
+					os.Exit(1)
+				}
+			}
+		}()
+		l.shutdown(sig == syscall.SIGUSR2)
+		return
+	}
+}
+
+// shutdown stops accepting connections, tells clients the server is restarting,
+// stops the monitor, flushes queued email and saves persistent session state
+// After a handoff the new process owns the listener, the outbox and the PID file entry,
+// and the monitor was already suspended before it started
+func (l *lifecycle) shutdown(handoff bool) {
+	defer close(l.done)
+	health.Notify("STOPPING=1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// srv.Shutdown drops connections whose first request arrives after it starts, so on a
+	// handoff stop accepting first and give just-accepted connections a moment to be read
+	if handoff {
+		l.handingOff.Store(true)
+		l.ln.Close()
+		time.Sleep(handoffGrace)
+	}
+
+	// Stop listening and wait for in-flight requests; event streams end when the hub closes
+	l.srv.RegisterOnShutdown(l.eventHub.Close)
+	if err := l.srv.Shutdown(ctx); err != nil {
+		logger.Warn("HTTP drain incomplete", "err", err)
+	}
+
+	// Terminal sockets are hijacked, so srv.Shutdown doesn't track them
+	l.ptyManager.Shutdown(ctx, handoff)
+
+	if !handoff {
+		l.monitorService.Shutdown(ctx)
+	}
+	l.cancelBase()
+	l.registry.UpdatePersistentSessionPaths()
+
+	if !handoff {
+		config.ClearPID()
+	}
+	logger.Info("Shutdown complete")
+}
+
+// handoff starts a copy of this binary that inherits the listening socket and waits until
+// it is serving. On success the caller drains this process; on error nothing has changed
+func (l *lifecycle) handoff() error {
+	tcpLn, ok := l.ln.(*net.TCPListener)
+	if !ok {
+		return errors.New("listener can't be handed over")
+	}
+	lnFile, err := tcpLn.File()
+	if err != nil {
+		return err
+	}
+	defer lnFile.Close()
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyR.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	l.registry.UpdatePersistentSessionPaths()
+
+	// The new process loads the outbox and starts notifying as soon as it runs, so stop
+	// ours first; it resumes if the new process doesn't take over
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	l.monitorService.Suspend(ctx)
+	cancel()
+	started := false
+	defer func() {
+		if !started {
+			l.monitorService.Resume()
+		}
+	}()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{lnFile, readyW} // fd 3 and 4
+	// systemd's WATCHDOG_PID names this process; the child becomes MAINPID and pings for itself
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, listenFDEnv+"=") && !strings.HasPrefix(kv, readyFDEnv+"=") && !strings.HasPrefix(kv, "WATCHDOG_PID=") {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	cmd.Env = append(cmd.Env, listenFDEnv+"=3", readyFDEnv+"=4")
+
+	err = cmd.Start()
+	readyW.Close()
+	if err != nil {
+		return err
+	}
+	logger.Info("Started new process", "pid", cmd.Process.Pid)
+
+	// The child writes one byte once it is serving; EOF means it exited first
+	ready := make(chan error, 1)
+	go func() {
+		_, err := readyR.Read(make([]byte, 1))
+		if errors.Is(err, io.EOF) {
+			err = errors.New("exited before becoming ready")
+		}
+		ready <- err
+	}()
+	select {
+	case err = <-ready:
+	case <-time.After(handoffTimeout):
+		err = fmt.Errorf("not ready after %s", handoffTimeout)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		go cmd.Wait()
+		return fmt.Errorf("new process (pid %d): %w", cmd.Process.Pid, err)
+	}
+	cmd.Process.Release()
+	started = true
+
+	// systemd (Type=notify) follows the new process as the service's main PID
+	health.Notify("MAINPID=" + strconv.Itoa(cmd.Process.Pid))
+	return nil
+}
+
+// listen returns the socket inherited from a restarting parent, or binds addr
+func listen(addr string) (net.Listener, error) {
+	fd, err := strconv.Atoi(os.Getenv(listenFDEnv))
+	if err != nil {
+		return net.Listen("tcp", addr)
+	}
+	os.Unsetenv(listenFDEnv)
+	f := os.NewFile(uintptr(fd), "listener")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("inherited listener: %w", err)
+	}
+	logger.Info("Took over listener from previous process", "addr", ln.Addr().String())
+	return ln, nil
+}
+
+// notifyParentReady tells a restarting parent that this process is serving
+func notifyParentReady() {
+	fd, err := strconv.Atoi(os.Getenv(readyFDEnv))
+	if err != nil {
+		return
+	}
+	os.Unsetenv(readyFDEnv)
+	f := os.NewFile(uintptr(fd), "ready")
+	f.Write([]byte{1})
+	f.Close()
+}
+
+// reload re-reads runtime.json now instead of waiting for the watcher
+// Subscribers apply whatever changed; an invalid file is rejected and the current config kept
+func (l *lifecycle) reload() {
+	if err := config.Reload(); err != nil {
+		logger.Error("Reload failed, keeping current config", "err", err)
+		return
+	}
+	logger.Info("Config reloaded")
+}
+
+// applyConfig applies the process-wide settings that can change without a restart:
+// logging, PIN and metrics token. The monitor service subscribes for its own sections
+func (l *lifecycle) applyConfig(old, cfg *config.Config) {
+	if !reflect.DeepEqual(old.Log, cfg.Log) {
+		if err := logging.Setup(logOptions(cfg)); err != nil {
+			logger.Error("Invalid logging config, keeping current", "err", err)
+		}
+	}
+	if cfg.PIN != "" && cfg.PIN != old.PIN {
+		if pin, err := config.ResolveSecret(cfg.PIN); err != nil {
+			logger.Error("Failed to resolve PIN, keeping current", "err", err)
+		} else {
+			auth.InitPINWithConfig(pin)
+			logger.Info("PIN changed")
+		}
+	}
+	if cfg.MetricsToken != old.MetricsToken {
+		metrics.SetToken(getEnvOrDefault("WINTERM_METRICS_TOKEN", cfg.MetricsToken, ""))
+	}
+	if cfg.Port != old.Port {
+		logger.Warn("Port change takes effect after a restart", "port", cfg.Port)
+	}
+}
+
+// logOptions builds logging options from cfg; -log-level and -log-format win when given
+// on the command line, then the WINTERM_LOG_* environment variables
+func logOptions(cfg *config.Config) logging.Options {
+	logCfg := cfg.Log
+	if logCfg == nil {
+		logCfg = &config.LogConfig{}
+	}
+	opts := logging.Options{
+		Level:      getEnvOrDefault("WINTERM_LOG_LEVEL", logCfg.Level, "info"),
+		Format:     getEnvOrDefault("WINTERM_LOG_FORMAT", logCfg.Format, "text"),
+		File:       logCfg.File,
+		Dir:        config.DefaultConfigDir(),
+		MaxSizeMB:  logCfg.MaxSizeMB,
+		MaxBackups: logCfg.MaxBackups,
+		Subsystems: logCfg.Subsystems,
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "log-level":
+			opts.Level = f.Value.String()
+		case "log-format":
+			opts.Format = f.Value.String()
+		}
+	})
+	return opts
+}
+
+// serve runs srv on l.ln until shutdown; it returns once the drain has finished
+func (l *lifecycle) serve() error {
+	err := l.srv.Serve(l.ln)
+	if !errors.Is(err, http.ErrServerClosed) && !(errors.Is(err, net.ErrClosed) && l.handingOff.Load()) {
+		return err
+	}
+	<-l.done
+	return nil
+}
@@ -1,27 +1,34 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"flag"
 	"fmt"
 	"io/fs"
-	"log"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
 	"strings"
-	"syscall"
 	"time"
 
+	"winterm-bridge/internal/actions"
 	"winterm-bridge/internal/api"
 	"winterm-bridge/internal/auth"
 	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/events"
+	"winterm-bridge/internal/health"
+	"winterm-bridge/internal/logging"
+	"winterm-bridge/internal/metrics"
 	"winterm-bridge/internal/monitor"
 	"winterm-bridge/internal/pty"
 	"winterm-bridge/internal/session"
 	"winterm-bridge/internal/tmux"
 )
 
+var logger = logging.For("server")
+
 // Version is set at build time via ldflags
 var Version = "dev"
 
@@ -35,15 +42,21 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Load config file
+	// Admin subcommands (status, stop, reset-pin, print-config, config validate, doctor)
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+	}
+
+	// Load config file; a missing one gives the defaults, an unreadable one is not replaced by them
 	cfg, err := config.Load()
 	if err != nil {
-		log.Printf("Warning: failed to load config: %v", err)
-		cfg = &config.Config{
-			Port:           "8080",
-			Autocreate:     true,
-			DefaultSession: "Main",
+		fmt.Fprintf(os.Stderr, "Failed to load %s: %v\n", config.ConfigPath(), err)
+		if !errors.Is(err, config.ErrKeyUnavailable) {
+			fmt.Fprintln(os.Stderr, "See 'winterm-bridge config validate'")
 		}
+		os.Exit(2)
 	}
 
 	// Parse command line flags (override config file)
@@ -51,44 +64,76 @@ func main() {
 	autocreate := flag.Bool("autocreate", cfg.Autocreate, "Auto-create default session on startup")
 	defaultSession := flag.String("default-session", getEnvOrDefault("", cfg.DefaultSession, "Main"), "Default session name")
 	defaultDir := flag.String("default-dir", getEnvOrDefault("HOME", cfg.DefaultDir, ""), "Default working directory")
+	logCfg := cfg.Log
+	if logCfg == nil {
+		logCfg = &config.LogConfig{}
+	}
+	flag.String("log-level", getEnvOrDefault("WINTERM_LOG_LEVEL", logCfg.Level, "info"), "Log level (debug, info, warn, error)")
+	flag.String("log-format", getEnvOrDefault("WINTERM_LOG_FORMAT", logCfg.Format, "text"), "Log format (text or json)")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), subcommandUsage+"\nFlags:\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	// Configure logging before anything else is logged
+	if err := logging.Setup(logOptions(cfg)); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging config: %v\n", err)
+		os.Exit(2)
+	}
+	if err := config.Validate(cfg); err != nil {
+		logger.Error("Invalid runtime.json, see 'winterm-bridge config validate'", "err", err)
+		os.Exit(2)
+	}
+
 	// Check tmux availability
 	version, err := tmux.CheckTmuxAvailable()
 	if err != nil {
-		log.Fatalf("tmux not found: %v", err)
+		logger.Error("tmux not found", "err", err)
+		os.Exit(1)
 	}
-	log.Printf("tmux detected: %s", version)
+	logger.Info("tmux detected", "version", version)
 
 	// Initialize PIN (priority: env var > config file > random)
-	pin := auth.InitPINWithConfig(cfg.PIN)
-	log.Printf("WinTerm-Bridge starting, PIN: %s", pin)
+	// The PIN never goes to the log; it is shown on an interactive terminal and kept in runtime.json
+	savedPIN, err := config.ResolveSecret(cfg.PIN)
+	if err != nil {
+		// A PIN kept in the environment or a file is the operator's; don't replace it with a random one
+		if os.Getenv("WINTERM_PIN") == "" {
+			logger.Error("Failed to resolve PIN from runtime.json", "err", err)
+			os.Exit(2)
+		}
+		logger.Warn("Failed to resolve PIN from runtime.json, using WINTERM_PIN", "err", err)
+	}
+	pinRef := cfg.PIN != savedPIN // runtime.json holds an env: or file: reference, not the PIN
+	pin := auth.InitPINWithConfig(savedPIN)
+	logger.Info("WinTerm-Bridge starting", "version", Version, "port", *port)
+	if fi, err := os.Stderr.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprintf(os.Stderr, "PIN: %s\n", pin)
+	}
 
 	// Update config with current runtime values and save
-	cfg.PIN = pin
+	if pin != savedPIN && !pinRef {
+		cfg.PIN = pin // An env:/file: reference is kept even when WINTERM_PIN overrides it
+	}
 	cfg.Port = *port
 	cfg.PID = os.Getpid()
 	if err := config.Save(cfg); err != nil {
-		log.Printf("Warning: failed to save config: %v", err)
+		logger.Warn("Failed to save config", "err", err)
 	}
 
-	// Setup signal handler to clear PID on exit (keep config file)
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigCh
-		config.ClearPID()
-		os.Exit(0)
-	}()
+	// Create event hub for session-list subscribers (/api/events)
+	eventHub := events.NewHub()
 
 	registry := session.NewRegistry()
+	registry.SetEventHub(eventHub)
 	registry.DiscoverExisting() // Discover existing tmux sessions on startup
 	registry.LoadPersistentSessions() // Load persistent sessions (creates ghost sessions if needed)
 
 	// Auto-create default session if enabled and no sessions exist
 	if *autocreate {
 		if err := registry.EnsureDefaultSession(*defaultSession, *defaultDir); err != nil {
-			log.Printf("Warning: failed to create default session: %v", err)
+			logger.Warn("Failed to create default session", "err", err)
 		}
 	}
 
@@ -97,31 +142,42 @@ func main() {
 
 	// Create PTY manager and handler
 	ptyManager := pty.NewManager(pty.Config{})
-	ptyHandler := pty.NewHandler(ptyManager, registry, tokenStore)
+	ptyHandler := pty.NewHandler(ptyManager, registry, tokenStore, eventHub)
+	registerMetrics(registry, ptyManager)
 
 	// Create AI monitor service (independent of web connections, uses tmux capture-pane)
 	monitorAdapter := monitor.NewRegistryAdapter(registry, ptyManager)
 	monitorService := monitor.NewService(monitorAdapter)
+	monitorService.SetEventHub(eventHub)
 	// Load AI config from file and apply
 	if aiCfg := config.GetAIMonitorConfig(); aiCfg != nil {
-		monitorService.UpdateConfig(monitor.Config{
-			Enabled:  aiCfg.Enabled,
-			Endpoint: aiCfg.Endpoint,
-			APIKey:   aiCfg.APIKey,
-			Model:    aiCfg.Model,
-			Lines:    aiCfg.Lines,
-			Interval: aiCfg.Interval,
-		})
+		monitorService.UpdateConfig(*aiCfg)
 	}
 
+	// Reply actions let notification links type into a session via tmux send-keys
+	actionManager := actions.NewManager(func(sessionID string, keys []byte) error {
+		sess := registry.Get(sessionID)
+		if sess == nil {
+			return fmt.Errorf("session not found")
+		}
+		return tmux.SendKeysToSession(sess.TmuxName, keys)
+	}, config.DefaultConfigDir())
+	monitorService.SetActionManager(actionManager)
+
 	// Create API handler
-	apiHandler := api.NewHandler(registry, tokenStore, ptyManager, monitorService)
+	apiHandler := api.NewHandler(registry, tokenStore, ptyManager, monitorService, eventHub)
+	apiHandler.SetActionManager(actionManager)
 
 	sub, err := fs.Sub(staticFS, "static")
 	if err != nil {
-		log.Fatalf("static FS error: %v", err)
+		logger.Error("Static FS error", "err", err)
+		os.Exit(1)
 	}
 
+	// Liveness and readiness probes for systemd and container healthchecks
+	checker := health.NewChecker(Version)
+	registerHealthChecks(checker, ptyManager, monitorService, sub)
+
 	mux := http.NewServeMux()
 
 	// HTTP REST API routes
@@ -139,7 +195,9 @@ func main() {
 	})
 	mux.HandleFunc("/api/sessions/", func(w http.ResponseWriter, r *http.Request) {
 		// Handle /api/sessions/{id}, /api/sessions/{id}/attach, /api/sessions/{id}/persist,
-		// /api/sessions/{id}/notify, /api/sessions/{id}/settings
+		// /api/sessions/{id}/notify, /api/sessions/{id}/settings (GET/PUT),
+		// /api/sessions/{id}/input, /api/sessions/{id}/exec, /api/sessions/{id}/wait,
+		// /api/sessions/{id}/capture
 		path := r.URL.Path
 
 		// Check if path ends with /persist
@@ -177,14 +235,49 @@ func main() {
 			return
 		}
 
-		// Handle /api/sessions/{id} (delete)
+		// Handle /api/sessions/{id}/ai-preview
+		if strings.HasSuffix(path, "/ai-preview") {
+			api.AuthMiddleware(apiHandler.HandleAIPreview)(w, r)
+			return
+		}
+
+		// Handle /api/sessions/{id}/input
+		if strings.HasSuffix(path, "/input") {
+			api.AuthMiddleware(apiHandler.HandleSessionInput)(w, r)
+			return
+		}
+
+		// Handle /api/sessions/{id}/exec
+		if strings.HasSuffix(path, "/exec") {
+			api.AuthMiddleware(apiHandler.HandleSessionExec)(w, r)
+			return
+		}
+
+		// Handle /api/sessions/{id}/wait
+		if strings.HasSuffix(path, "/wait") {
+			api.AuthMiddleware(apiHandler.HandleSessionWait)(w, r)
+			return
+		}
+
+		// Handle /api/sessions/{id}/capture
+		if strings.HasSuffix(path, "/capture") {
+			api.AuthMiddleware(apiHandler.HandleCaptureSession)(w, r)
+			return
+		}
+
+		// Handle /api/sessions/{id} (delete, rename)
 		if r.Method == http.MethodDelete {
 			api.AuthMiddleware(apiHandler.HandleDeleteSession)(w, r)
+		} else if r.Method == http.MethodPatch {
+			api.AuthMiddleware(apiHandler.HandleRenameSession)(w, r)
 		} else {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
 
+	// Server-sent event stream for session-list subscribers
+	mux.HandleFunc("/api/events", api.StreamAuthMiddleware(apiHandler.HandleEvents))
+
 	// WebSocket endpoint for terminal
 	mux.HandleFunc("/ws", ptyHandler.ServeWS)
 
@@ -196,24 +289,82 @@ func main() {
 	mux.HandleFunc("/api/ai/config", api.AuthMiddleware(apiHandler.HandleAIConfig))
 	mux.HandleFunc("/api/ai/test", api.AuthMiddleware(apiHandler.HandleAITest))
 	mux.HandleFunc("/api/ai/summaries", api.AuthMiddleware(apiHandler.HandleAISummaries))
+	mux.HandleFunc("/api/ai/stats", api.AuthMiddleware(apiHandler.HandleAIStats))
+	mux.HandleFunc("/api/ai/summaries/", api.AuthMiddleware(apiHandler.HandleAISummaryHistory))
 
 	// Email notification API endpoints
 	mux.HandleFunc("/api/email/config", api.AuthMiddleware(apiHandler.HandleEmailConfig))
 	mux.HandleFunc("/api/email/test", api.AuthMiddleware(apiHandler.HandleEmailTest))
+	mux.HandleFunc("/api/email/outbox", api.AuthMiddleware(apiHandler.HandleEmailOutbox))
+
+	// Notification channel API endpoints
+	mux.HandleFunc("/api/notify/channels", api.AuthMiddleware(apiHandler.HandleNotifyChannels))
+	mux.HandleFunc("/api/notify/test", api.AuthMiddleware(apiHandler.HandleNotifyTest))
+	mux.HandleFunc("/api/notify/rules", api.AuthMiddleware(apiHandler.HandleNotifyRules))
+	mux.HandleFunc("/api/push/vapid-key", api.AuthMiddleware(apiHandler.HandlePushVAPIDKey))
+	mux.HandleFunc("/api/push/subscriptions", api.AuthMiddleware(apiHandler.HandlePushSubscriptions))
+
+	// Reply actions (token-authenticated, usable from email links and webhook callbacks)
+	mux.HandleFunc("/api/actions/", apiHandler.HandleAction)
+	mux.HandleFunc("/api/actions/audit", api.AuthMiddleware(apiHandler.HandleActionAudit))
+
+	// Health probes (unauthenticated)
+	mux.HandleFunc("/healthz", checker.HandleHealthz)
+	mux.HandleFunc("/readyz", checker.HandleReadyz)
+
+	// Prometheus metrics (separate bearer token, disabled when unset)
+	metrics.SetToken(getEnvOrDefault("WINTERM_METRICS_TOKEN", cfg.MetricsToken, ""))
+	mux.HandleFunc("/metrics", metrics.Handler())
 
 	// Static files with SPA fallback (serves index.html for unknown routes)
 	mux.Handle("/", spaHandler(http.FS(sub)))
 
+	// Request contexts derive from baseCtx so shutdown can end long-lived handlers
+	baseCtx, cancelBase := context.WithCancel(context.Background())
 	srv := &http.Server{
 		Addr:              ":" + *port,
-		Handler:           mux,
+		Handler:           logging.Middleware(mux),
 		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
 	}
 
 	go registry.Cleanup(1 * time.Minute)
 
-	log.Printf("Listening on %s", srv.Addr)
-	log.Fatal(srv.ListenAndServe())
+	// Bind the port, or take over the socket when restarted by a previous process
+	ln, err := listen(srv.Addr)
+	if err != nil {
+		logger.Error("Failed to listen", "addr", srv.Addr, "err", err)
+		os.Exit(1)
+	}
+	logger.Info("Listening", "addr", ln.Addr().String())
+
+	// SIGINT/SIGTERM drain connections and exit; SIGHUP reloads runtime.json (PID is cleared, config kept)
+	// SIGUSR2 restarts without dropping the port (see lifecycle.handoff)
+	lc := &lifecycle{
+		srv:            srv,
+		ln:             ln,
+		cancelBase:     cancelBase,
+		eventHub:       eventHub,
+		ptyManager:     ptyManager,
+		monitorService: monitorService,
+		registry:       registry,
+		done:           make(chan struct{}),
+	}
+	go lc.run()
+
+	// Apply edits to runtime.json while running; API saves go through the same store
+	config.Subscribe(lc.applyConfig)
+	go config.Watch(baseCtx)
+
+	// Tell systemd (Type=notify) we're up, and keep its watchdog fed while ready
+	health.Notify("READY=1")
+	notifyParentReady()
+	go checker.Watchdog(baseCtx)
+
+	if err := lc.serve(); err != nil {
+		logger.Error("Server stopped", "err", err)
+		os.Exit(1)
+	}
 }
 
 // getEnvOrDefault returns env value, then config value, then default value
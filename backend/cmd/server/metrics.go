@@ -0,0 +1,40 @@
+package main
+
+import (
+	"winterm-bridge/internal/metrics"
+	"winterm-bridge/internal/pty"
+	"winterm-bridge/internal/session"
+)
+
+// registerMetrics adds gauges computed from live session and PTY state at scrape time
+func registerMetrics(registry *session.Registry, ptyManager *pty.Manager) {
+	metrics.NewGaugeVecFunc("winterm_sessions", "Sessions by state", "state", func() map[string]float64 {
+		counts := map[string]float64{"active": 0, "detached": 0, "ghost": 0}
+		for _, s := range registry.ListAll() {
+			switch clients, _ := ptyManager.ClientActivity(s.ID); {
+			case s.IsGhost:
+				counts["ghost"]++
+			case clients > 0:
+				counts["active"]++
+			default:
+				counts["detached"]++
+			}
+		}
+		return counts
+	})
+	metrics.NewGaugeFunc("winterm_sessions_persistent", "Sessions marked persistent", func() float64 {
+		n := 0
+		for _, s := range registry.ListAll() {
+			if s.IsPersistent {
+				n++
+			}
+		}
+		return float64(n)
+	})
+	metrics.NewGaugeFunc("winterm_websocket_connections", "Terminal WebSocket connections currently open", func() float64 {
+		return float64(ptyManager.ConnectionCount())
+	})
+	metrics.NewGaugeFunc("winterm_pty_instances", "Running PTY instances (tmux attach processes)", func() float64 {
+		return float64(ptyManager.InstanceCount())
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/creack/pty"
+	"github.com/gorilla/websocket"
+)
+
+// detachKey ends an attach session (Ctrl-])
+const detachKey = 0x1d
+
+// dialSession requests an attachment token and opens the session WebSocket
+func (c *client) dialSession(sessionID string) (*websocket.Conn, error) {
+	var resp struct {
+		WsURL string `json:"ws_url"`
+	}
+	if err := c.do(http.MethodPost, "/api/sessions/"+sessionID+"/attach", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(c.server + resp.WsURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	return conn, nil
+}
+
+// attach connects the local terminal to a session until Ctrl-] or the session ends
+func (c *client) attach(sessionID string) error {
+	conn, err := c.dialSession(sessionID)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	saved, err := exec.Command("sh", "-c", "stty -g < /dev/tty").Output()
+	if err != nil {
+		return fmt.Errorf("stdin is not a terminal")
+	}
+	if err := stty("raw", "-echo"); err != nil {
+		return err
+	}
+	defer stty(strings.TrimSpace(string(saved)))
+
+	// All writes happen on this goroutine; gorilla allows one writer at a time
+	writes := make(chan func() error, 16)
+	done := make(chan error, 2)
+
+	sendResize := func() {
+		if rows, cols, err := pty.Getsize(os.Stdin); err == nil {
+			msg, _ := json.Marshal(map[string]any{"type": "resize", "cols": cols, "rows": rows})
+			conn.WriteMessage(websocket.TextMessage, msg)
+		}
+	}
+	sendResize()
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+
+	// Terminal output
+	go func() {
+		for {
+			kind, data, err := conn.ReadMessage()
+			if err != nil {
+				done <- nil
+				return
+			}
+			if kind == websocket.BinaryMessage {
+				os.Stdout.Write(data)
+			}
+		}
+	}()
+
+	// Keyboard input
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				done <- err
+				return
+			}
+			data := append([]byte(nil), buf[:n]...)
+			if i := strings.IndexByte(string(data), detachKey); i >= 0 {
+				data = data[:i]
+				if len(data) > 0 {
+					writes <- func() error { return conn.WriteMessage(websocket.BinaryMessage, data) }
+				}
+				done <- nil
+				return
+			}
+			writes <- func() error { return conn.WriteMessage(websocket.BinaryMessage, data) }
+		}
+	}()
+
+	for {
+		select {
+		case write := <-writes:
+			if err := write(); err != nil {
+				return nil
+			}
+		case <-winch:
+			sendResize()
+		case err := <-done:
+			// Flush input typed just before detaching
+			for len(writes) > 0 {
+				_ = (<-writes)()
+			}
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			fmt.Fprint(os.Stdout, "\r\n[detached]\r\n")
+			return err
+		}
+	}
+}
+
+// follow streams a session's output to stdout without sending input or resizing
+func (c *client) follow(sessionID string) error {
+	conn, err := c.dialSession(sessionID)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		conn.Close()
+	}()
+
+	for {
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			return nil
+		}
+		if kind == websocket.BinaryMessage {
+			os.Stdout.Write(data)
+		}
+	}
+}
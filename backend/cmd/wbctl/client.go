@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"winterm-bridge/internal/config"
+)
+
+// credentials is the cached login, stored next to the server's runtime.json
+type credentials struct {
+	Server    string    `json:"server"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func credentialsPath() string {
+	return filepath.Join(config.DefaultConfigDir(), "wbctl.json")
+}
+
+func loadCredentials() *credentials {
+	data, err := os.ReadFile(credentialsPath())
+	if err != nil {
+		return &credentials{}
+	}
+	var c credentials
+	if err := json.Unmarshal(data, &c); err != nil {
+		return &credentials{}
+	}
+	return &c
+}
+
+func saveCredentials(c *credentials) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(credentialsPath()), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(credentialsPath(), data, 0600)
+}
+
+// localServer returns the URL and PIN of a bridge running on this machine, from runtime.json
+func localServer() (server, pin string) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "http://127.0.0.1:8080", ""
+	}
+	pin, _ = config.ResolveSecret(cfg.PIN)
+	return "http://127.0.0.1:" + cfg.Port, pin
+}
+
+// apiError is a non-2xx API response
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.Status)
+}
+
+// client talks to the bridge REST API with a cached bearer token
+type client struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+// newClient resolves the server and token from flags, environment and the credentials cache
+// Against the local server it logs in with the PIN from runtime.json when no valid token is cached
+func newClient(server string) (*client, error) {
+	creds := loadCredentials()
+	if server == "" {
+		server = os.Getenv("WBCTL_SERVER")
+	}
+	if server == "" {
+		server = creds.Server
+	}
+	local, pin := localServer()
+	if server == "" {
+		server = local
+	}
+	server = strings.TrimSuffix(server, "/")
+
+	c := &client{server: server, http: &http.Client{}}
+	if token := os.Getenv("WBCTL_TOKEN"); token != "" {
+		c.token = token
+		return c, nil
+	}
+	if creds.Server == server && creds.Token != "" && time.Now().Before(creds.ExpiresAt) {
+		c.token = creds.Token
+		return c, nil
+	}
+	if server == local && pin != "" {
+		if err := c.login(pin); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("not logged in to %s; run: wbctl login -server %s", server, server)
+}
+
+// login exchanges a PIN for a token and caches it
+func (c *client) login(pin string) error {
+	var resp struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := c.do(http.MethodPost, "/api/auth", map[string]string{"pin": pin}, &resp); err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	c.token = resp.Token
+	return saveCredentials(&credentials{Server: c.server, Token: resp.Token, ExpiresAt: resp.ExpiresAt})
+}
+
+// do sends a JSON request and decodes a JSON response into out (if non-nil)
+func (c *client) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.server+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			msg = e.Error
+		}
+		return &apiError{Status: resp.StatusCode, Message: msg}
+	}
+	if out != nil && len(data) > 0 {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// sessionInfo mirrors api.SessionInfo
+type sessionInfo struct {
+	ID           string    `json:"id"`
+	State        string    `json:"state"`
+	LastActive   time.Time `json:"last_active"`
+	Title        string    `json:"title"`
+	TmuxName     string    `json:"tmux_name"`
+	CurrentPath  string    `json:"current_path"`
+	IsPersistent bool      `json:"is_persistent"`
+	IsGhost      bool      `json:"is_ghost"`
+}
+
+func (c *client) sessions() ([]sessionInfo, error) {
+	var resp struct {
+		Sessions []sessionInfo `json:"sessions"`
+	}
+	if err := c.do(http.MethodGet, "/api/sessions", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Sessions, nil
+}
+
+// resolve finds a session by ID, unique ID prefix, title or tmux name
+func (c *client) resolve(ref string) (*sessionInfo, error) {
+	sessions, err := c.sessions()
+	if err != nil {
+		return nil, err
+	}
+	var matches []*sessionInfo
+	for i := range sessions {
+		s := &sessions[i]
+		if s.ID == ref || s.Title == ref || s.TmuxName == ref {
+			return s, nil
+		}
+		if len(ref) >= 4 && strings.HasPrefix(s.ID, ref) {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no session matches %q", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%q matches %d sessions; use a longer ID", ref, len(matches))
+	}
+}
+
+// readSecret reads a line from the terminal without echo (best effort via stty)
+func readSecret(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if err := stty("-echo"); err == nil {
+		defer func() {
+			stty("echo")
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// stty changes the controlling terminal's settings
+func stty(args ...string) error {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
@@ -0,0 +1,485 @@
+// Command wbctl is a command-line client for the WinTerm-Bridge REST API
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"winterm-bridge/internal/email"
+)
+
+const usage = `wbctl - command-line client for WinTerm-Bridge
+
+Usage: wbctl [-server URL] <command> [args]
+
+Commands:
+  login [-pin PIN]                    Log in and cache a token (prompts for the PIN)
+  logout                              Forget the cached token
+  ls [-json]                          List sessions with their AI status tags
+  new [-dir DIR] [title]              Create a session
+  rm <session>                        Delete a session
+  rename <session> <title>            Change a session's title
+  persist [-off] <session>            Keep a session across server restarts (or stop)
+  send [-keys K,..] [-hex HEX] [-enter] <session> [text]
+                                      Type text, raw bytes and tmux keys (Enter, C-c, Up)
+  exec [-timeout S] <session> <cmd>   Run a shell command and print its output; exits with its status
+  wait [-pattern RE] [-idle S] [-tag T] [-timeout S] <session>
+                                      Wait for output, idleness or an AI status tag
+  capture [-lines N] [-ansi] <session>
+                                      Print the pane content
+  tail [-n N] [-f] <session>          Print the last lines; -f follows live output
+  attach <session>                    Attach this terminal (Ctrl-] detaches)
+  share <session>                     Print a link that opens the session in a browser
+  config get <ai|email>               Show settings
+  config set <ai|email> key=value...  Change settings (values are parsed as JSON when possible)
+
+A session is an ID, a unique ID prefix, a title or a tmux name.
+The server defaults to $WBCTL_SERVER, the last login, or the local bridge from runtime.json.
+$WBCTL_TOKEN overrides the cached token.
+`
+
+func main() {
+	global := flag.NewFlagSet("wbctl", flag.ExitOnError)
+	server := global.String("server", "", "Bridge URL, e.g. https://host:8080")
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	global.Parse(os.Args[1:])
+
+	args := global.Args()
+	if len(args) == 0 {
+		global.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*server, args[0], args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "wbctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(server, command string, args []string) error {
+	switch command {
+	case "login":
+		return cmdLogin(server, args)
+	case "logout":
+		if err := os.Remove(credentialsPath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	}
+
+	handlers := map[string]func(*client, []string) error{
+		"ls":      cmdList,
+		"new":     cmdNew,
+		"rm":      cmdRemove,
+		"rename":  cmdRename,
+		"persist": cmdPersist,
+		"send":    cmdSend,
+		"exec":    cmdExec,
+		"wait":    cmdWait,
+		"capture": cmdCapture,
+		"tail":    cmdTail,
+		"attach":  cmdAttach,
+		"share":   cmdShare,
+		"config":  cmdConfig,
+	}
+	handler, ok := handlers[command]
+	if !ok {
+		return fmt.Errorf("unknown command %q (see wbctl help)", command)
+	}
+	c, err := newClient(server)
+	if err != nil {
+		return err
+	}
+	return handler(c, args)
+}
+
+// parseFlags parses a subcommand's flags and checks the number of positional arguments
+func parseFlags(fs *flag.FlagSet, args []string, minArgs int, synopsis string) ([]string, error) {
+	fs.Usage = func() { fmt.Fprintf(os.Stderr, "Usage: wbctl %s\n", synopsis) }
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() < minArgs {
+		fs.Usage()
+		return nil, fmt.Errorf("missing arguments")
+	}
+	return fs.Args(), nil
+}
+
+func cmdLogin(server string, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	pin := fs.String("pin", "", "PIN (prompted when omitted)")
+	if _, err := parseFlags(fs, args, 0, "login [-pin PIN]"); err != nil {
+		return err
+	}
+
+	if server == "" {
+		server = os.Getenv("WBCTL_SERVER")
+	}
+	if server == "" {
+		server, _ = localServer()
+	}
+	if *pin == "" {
+		p, err := readSecret("PIN: ")
+		if err != nil {
+			return err
+		}
+		*pin = p
+	}
+
+	c := &client{server: strings.TrimSuffix(server, "/"), http: &http.Client{}}
+	if err := c.login(*pin); err != nil {
+		return err
+	}
+	fmt.Printf("Logged in to %s\n", c.server)
+	return nil
+}
+
+func cmdList(c *client, args []string) error {
+	fs := flag.NewFlagSet("ls", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print JSON")
+	if _, err := parseFlags(fs, args, 0, "ls [-json]"); err != nil {
+		return err
+	}
+
+	sessions, err := c.sessions()
+	if err != nil {
+		return err
+	}
+	var summaries struct {
+		Summaries map[string]struct {
+			Tag         string `json:"tag"`
+			Description string `json:"description"`
+		} `json:"summaries"`
+	}
+	// Summaries are optional; the AI monitor may be off
+	_ = c.do(http.MethodGet, "/api/ai/summaries", nil, &summaries)
+
+	if *asJSON {
+		type entry struct {
+			sessionInfo
+			Tag         string `json:"tag,omitempty"`
+			Description string `json:"description,omitempty"`
+		}
+		out := make([]entry, 0, len(sessions))
+		for _, s := range sessions {
+			sum := summaries.Summaries[s.ID]
+			out = append(out, entry{s, sum.Tag, sum.Description})
+		}
+		return printJSON(out)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tSTATE\tSTATUS\tACTIVE\tPATH")
+	for _, s := range sessions {
+		state := s.State
+		if s.IsGhost {
+			state = "ghost"
+		} else if s.IsPersistent {
+			state += "*"
+		}
+		tag := summaries.Summaries[s.ID].Tag
+		if tag == "" {
+			tag = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", s.ID[:8], s.Title, state, tag, ago(s.LastActive), s.CurrentPath)
+	}
+	return tw.Flush()
+}
+
+func cmdNew(c *client, args []string) error {
+	fs := flag.NewFlagSet("new", flag.ContinueOnError)
+	dir := fs.String("dir", "", "Working directory")
+	rest, err := parseFlags(fs, args, 0, "new [-dir DIR] [title]")
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Session sessionInfo `json:"session"`
+	}
+	body := map[string]string{"title": strings.Join(rest, " "), "working_directory": *dir}
+	if err := c.do(http.MethodPost, "/api/sessions", body, &resp); err != nil {
+		return err
+	}
+	fmt.Println(resp.Session.ID)
+	return nil
+}
+
+func cmdRemove(c *client, args []string) error {
+	sess, err := sessionArg(c, flag.NewFlagSet("rm", flag.ContinueOnError), args, "rm <session>")
+	if err != nil {
+		return err
+	}
+	return c.do(http.MethodDelete, "/api/sessions/"+sess.ID, nil, nil)
+}
+
+func cmdRename(c *client, args []string) error {
+	fs := flag.NewFlagSet("rename", flag.ContinueOnError)
+	rest, err := parseFlags(fs, args, 2, "rename <session> <title>")
+	if err != nil {
+		return err
+	}
+	sess, err := c.resolve(rest[0])
+	if err != nil {
+		return err
+	}
+	return c.do(http.MethodPatch, "/api/sessions/"+sess.ID, map[string]string{"title": strings.Join(rest[1:], " ")}, nil)
+}
+
+func cmdPersist(c *client, args []string) error {
+	fs := flag.NewFlagSet("persist", flag.ContinueOnError)
+	off := fs.Bool("off", false, "Stop persisting the session")
+	sess, err := sessionArg(c, fs, args, "persist [-off] <session>")
+	if err != nil {
+		return err
+	}
+	method := http.MethodPost
+	if *off {
+		method = http.MethodDelete
+	}
+	return c.do(method, "/api/sessions/"+sess.ID+"/persist", nil, nil)
+}
+
+func cmdSend(c *client, args []string) error {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	keys := fs.String("keys", "", "Comma-separated tmux keys sent after the text, e.g. Enter or C-c")
+	hexBytes := fs.String("hex", "", "Raw bytes as hex, sent after the text")
+	enter := fs.Bool("enter", false, "Press Enter at the end")
+	rest, err := parseFlags(fs, args, 1, "send [-keys K,..] [-hex HEX] [-enter] <session> [text]")
+	if err != nil {
+		return err
+	}
+	sess, err := c.resolve(rest[0])
+	if err != nil {
+		return err
+	}
+
+	req := map[string]any{"text": strings.Join(rest[1:], " "), "hex": *hexBytes}
+	var keyList []string
+	if *keys != "" {
+		keyList = strings.Split(*keys, ",")
+	}
+	if *enter {
+		keyList = append(keyList, "Enter")
+	}
+	req["keys"] = keyList
+	return c.do(http.MethodPost, "/api/sessions/"+sess.ID+"/input", req, nil)
+}
+
+func cmdExec(c *client, args []string) error {
+	fs := flag.NewFlagSet("exec", flag.ContinueOnError)
+	timeout := fs.Int("timeout", 30, "Seconds to wait")
+	prompt := fs.String("prompt", "", "Wait for this prompt regex instead of markers (\"default\" for a generic prompt)")
+	rest, err := parseFlags(fs, args, 2, "exec [-timeout S] [-prompt RE] <session> <command>")
+	if err != nil {
+		return err
+	}
+	sess, err := c.resolve(rest[0])
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Output   string `json:"output"`
+		ExitCode *int   `json:"exit_code"`
+		TimedOut bool   `json:"timed_out"`
+	}
+	req := map[string]any{"command": strings.Join(rest[1:], " "), "timeout": *timeout, "prompt": *prompt}
+	if err := c.do(http.MethodPost, "/api/sessions/"+sess.ID+"/exec", req, &result); err != nil {
+		return err
+	}
+	if result.Output != "" {
+		fmt.Println(result.Output)
+	}
+	if result.TimedOut {
+		return fmt.Errorf("timed out after %ds", *timeout)
+	}
+	if result.ExitCode != nil && *result.ExitCode != 0 {
+		os.Exit(*result.ExitCode)
+	}
+	return nil
+}
+
+func cmdWait(c *client, args []string) error {
+	fs := flag.NewFlagSet("wait", flag.ContinueOnError)
+	pattern := fs.String("pattern", "", "Regex to wait for in new output")
+	idle := fs.Int("idle", 0, "Seconds without output")
+	tag := fs.String("tag", "", "AI status tag")
+	timeout := fs.Int("timeout", 30, "Seconds to wait")
+	lookback := fs.Int("lookback", 0, "Also search this many earlier lines")
+	sess, err := sessionArg(c, fs, args, "wait [-pattern RE] [-idle S] [-tag T] [-timeout S] [-lookback N] <session>")
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Matched bool   `json:"matched"`
+		Reason  string `json:"reason"`
+		Match   string `json:"match"`
+	}
+	req := map[string]any{"pattern": *pattern, "idle": *idle, "tag": *tag, "timeout": *timeout, "lookback": *lookback}
+	if err := c.do(http.MethodPost, "/api/sessions/"+sess.ID+"/wait", req, &result); err != nil {
+		return err
+	}
+	if !result.Matched {
+		return fmt.Errorf("timed out after %ds", *timeout)
+	}
+	if result.Match != "" {
+		fmt.Println(result.Match)
+	} else {
+		fmt.Println(result.Reason)
+	}
+	return nil
+}
+
+func cmdCapture(c *client, args []string) error {
+	fs := flag.NewFlagSet("capture", flag.ContinueOnError)
+	lines := fs.Int("lines", 0, "Last N non-empty lines (0 = whole pane)")
+	ansi := fs.Bool("ansi", false, "Keep color escape sequences")
+	sess, err := sessionArg(c, fs, args, "capture [-lines N] [-ansi] <session>")
+	if err != nil {
+		return err
+	}
+	return printCapture(c, sess.ID, *lines, *ansi)
+}
+
+func cmdTail(c *client, args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	n := fs.Int("n", 20, "Number of lines")
+	follow := fs.Bool("f", false, "Follow live output (a read-only attach)")
+	sess, err := sessionArg(c, fs, args, "tail [-n N] [-f] <session>")
+	if err != nil {
+		return err
+	}
+	if *follow {
+		return c.follow(sess.ID)
+	}
+	return printCapture(c, sess.ID, *n, false)
+}
+
+func cmdAttach(c *client, args []string) error {
+	sess, err := sessionArg(c, flag.NewFlagSet("attach", flag.ContinueOnError), args, "attach <session>")
+	if err != nil {
+		return err
+	}
+	return c.attach(sess.ID)
+}
+
+func cmdShare(c *client, args []string) error {
+	sess, err := sessionArg(c, flag.NewFlagSet("share", flag.ContinueOnError), args, "share <session>")
+	if err != nil {
+		return err
+	}
+	// Prefer the configured public URL; fall back to the address wbctl talks to
+	var emailCfg struct {
+		PublicURL string `json:"public_url"`
+	}
+	_ = c.do(http.MethodGet, "/api/email/config", nil, &emailCfg)
+	base := emailCfg.PublicURL
+	if base == "" {
+		base = c.server
+	}
+	fmt.Println(email.SessionLink(base, sess.ID))
+	return nil
+}
+
+func cmdConfig(c *client, args []string) error {
+	if len(args) < 2 || (args[0] != "get" && args[0] != "set") {
+		return fmt.Errorf("usage: wbctl config get|set <ai|email> [key=value...]")
+	}
+	var path string
+	switch args[1] {
+	case "ai":
+		path = "/api/ai/config"
+	case "email":
+		path = "/api/email/config"
+	default:
+		return fmt.Errorf("unknown settings section %q (ai or email)", args[1])
+	}
+
+	if args[0] == "get" {
+		var cfg map[string]any
+		if err := c.do(http.MethodGet, path, nil, &cfg); err != nil {
+			return err
+		}
+		return printJSON(cfg)
+	}
+
+	if len(args) < 3 {
+		return fmt.Errorf("usage: wbctl config set %s key=value...", args[1])
+	}
+	update := make(map[string]any)
+	for _, kv := range args[2:] {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("expected key=value, got %q", kv)
+		}
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			v = value
+		}
+		update[key] = v
+	}
+	var cfg map[string]any
+	if err := c.do(http.MethodPost, path, update, &cfg); err != nil {
+		return err
+	}
+	return printJSON(cfg)
+}
+
+// sessionArg parses flags and resolves the single session argument
+func sessionArg(c *client, fs *flag.FlagSet, args []string, synopsis string) (*sessionInfo, error) {
+	rest, err := parseFlags(fs, args, 1, synopsis)
+	if err != nil {
+		return nil, err
+	}
+	return c.resolve(rest[0])
+}
+
+func printCapture(c *client, sessionID string, lines int, ansi bool) error {
+	path := fmt.Sprintf("/api/sessions/%s/capture?lines=%d", sessionID, lines)
+	if ansi {
+		path += "&ansi=1"
+	}
+	var resp struct {
+		Content string `json:"content"`
+	}
+	if err := c.do(http.MethodGet, path, nil, &resp); err != nil {
+		return err
+	}
+	fmt.Println(strings.TrimRight(resp.Content, "\n"))
+	return nil
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// ago formats a time as a short relative duration
+func ago(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case t.IsZero():
+		return "-"
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
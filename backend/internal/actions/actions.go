@@ -0,0 +1,336 @@
+package actions
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long an action link stays valid
+const DefaultTTL = 15 * time.Minute
+
+// Token errors
+var (
+	ErrInvalidToken = errors.New("invalid action token")
+	ErrExpired      = errors.New("action link has expired")
+	ErrUsed         = errors.New("action link has already been used")
+)
+
+// Action is a one-click reply attached to a notification
+type Action struct {
+	Label string `json:"label"`
+	Token string `json:"token"`
+	URL   string `json:"url,omitempty"` // Set when a public base URL is configured
+}
+
+// Info describes what a token will do once executed
+type Info struct {
+	SessionID string    `json:"session_id"`
+	Label     string    `json:"label"`
+	Expires   time.Time `json:"expires"`
+}
+
+// choice is a predefined reply for a status tag
+type choice struct {
+	label string
+	keys  string
+}
+
+// tagChoices lists the replies offered for tags that wait on the user
+// Yes/no prompts are usually line-buffered and need Enter; selection menus react to a single key
+var tagChoices = map[string][]choice{
+	"需输入": {{"y", "y\r"}, {"n", "n\r"}, {"Enter", "\r"}},
+	"需选择": {{"1", "1"}, {"2", "2"}, {"3", "3"}},
+}
+
+// claims is the signed token payload
+type claims struct {
+	SessionID string `json:"s"`
+	Label     string `json:"l"`
+	Keys      string `json:"k"`
+	Expires   int64  `json:"e"`
+	Nonce     string `json:"n"`
+}
+
+// SendFunc writes keystrokes into a session
+type SendFunc func(sessionID string, keys []byte) error
+
+// Files kept in the manager's directory
+const (
+	keyFile  = "action.key"       // HMAC signing key, so links outlive a restart
+	usedFile = "action_used.json" // Redeemed nonces, so a restart doesn't make links reusable
+)
+
+// Manager issues and redeems signed, single-use action tokens
+// The signing key and redeemed nonces are kept on disk, so links sent before a restart
+// still work, and still only once
+type Manager struct {
+	key      []byte
+	ttl      time.Duration
+	send     SendFunc
+	audit    *auditLog
+	used     map[string]time.Time // Redeemed nonces until they expire
+	usedPath string
+	mu       sync.Mutex
+	now      func() time.Time
+}
+
+// NewManager creates a manager that delivers keystrokes with send and keeps its key,
+// redeemed nonces and audit log in dir
+func NewManager(send SendFunc, dir string) *Manager {
+	key, err := loadKey(filepath.Join(dir, keyFile))
+	if err != nil {
+		logger.Warn("Failed to load action key, links will stop working on restart", "err", err)
+		key = newKey()
+	}
+	return &Manager{
+		key:      key,
+		ttl:      DefaultTTL,
+		send:     send,
+		audit:    newAuditLog(dir),
+		used:     make(map[string]time.Time),
+		usedPath: filepath.Join(dir, usedFile),
+		now:      time.Now,
+	}
+}
+
+// loadKey reads the signing key, creating it with mode 0600 if it doesn't exist
+func loadKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		key := newKey()
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+		// O_EXCL: if another process created it meanwhile, use that one
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			return loadKey(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		_, err = f.WriteString(hex.EncodeToString(key) + "\n")
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("%s is accessible by other users (mode %o); chmod 600 it", path, info.Mode().Perm())
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%s: not a 32-byte hex key", path)
+	}
+	return key, nil
+}
+
+func newKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("actions: failed to generate key: %v", err))
+	}
+	return key
+}
+
+// ForTag returns the reply actions for a tag, with URLs under baseURL when it is set
+func (m *Manager) ForTag(sessionID, tag, baseURL string) []Action {
+	choices := tagChoices[tag]
+	if len(choices) == 0 {
+		return nil
+	}
+	out := make([]Action, 0, len(choices))
+	for _, c := range choices {
+		token, err := m.Issue(sessionID, c.label, c.keys)
+		if err != nil {
+			continue
+		}
+		a := Action{Label: c.label, Token: token}
+		if baseURL != "" {
+			a.URL = strings.TrimSuffix(baseURL, "/") + "/api/actions/" + token
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// Issue signs a token that sends keys to a session when redeemed
+func (m *Manager) Issue(sessionID, label, keys string) (string, error) {
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims{
+		SessionID: sessionID,
+		Label:     label,
+		Keys:      keys,
+		Expires:   m.now().Add(m.ttl).Unix(),
+		Nonce:     hex.EncodeToString(nonce),
+	})
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + m.sign(body), nil
+}
+
+// Inspect verifies a token without redeeming it
+func (m *Manager) Inspect(token string) (*Info, error) {
+	c, err := m.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.loadUsedLocked()
+	_, used := m.used[c.Nonce]
+	m.mu.Unlock()
+	if used {
+		return nil, ErrUsed
+	}
+	return c.info(), nil
+}
+
+// Execute redeems a token: it is marked used, the keys are sent and the attempt is audited
+// remote and userAgent identify the caller in the audit log
+func (m *Manager) Execute(token, remote, userAgent string) (*Info, error) {
+	entry := AuditEntry{Time: m.now(), Remote: remote, UserAgent: userAgent}
+
+	c, err := m.verify(token)
+	if err == nil {
+		err = m.consume(c)
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		m.audit.add(entry)
+		return nil, err
+	}
+
+	entry.SessionID = c.SessionID
+	entry.Label = c.Label
+	if err := m.send(c.SessionID, []byte(c.Keys)); err != nil {
+		entry.Error = err.Error()
+		m.audit.add(entry)
+		return nil, fmt.Errorf("failed to send keys: %w", err)
+	}
+
+	entry.OK = true
+	m.audit.add(entry)
+	return c.info(), nil
+}
+
+// Audit returns the most recent audit entries, newest first
+func (m *Manager) Audit(limit int) []AuditEntry {
+	return m.audit.recent(limit)
+}
+
+// verify checks the signature and expiry of a token
+func (m *Manager) verify(token string) (*claims, error) {
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(m.sign(body))) {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil || c.SessionID == "" || c.Nonce == "" {
+		return nil, ErrInvalidToken
+	}
+	if m.now().Unix() > c.Expires {
+		return nil, ErrExpired
+	}
+	return &c, nil
+}
+
+// consume marks a token's nonce as used, failing if it already was
+// Nonces redeemed by an earlier process are read from disk first
+func (m *Manager) consume(c *claims) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loadUsedLocked()
+
+	// Forget nonces whose tokens have expired anyway
+	now := m.now()
+	for nonce, exp := range m.used {
+		if now.After(exp) {
+			delete(m.used, nonce)
+		}
+	}
+
+	if _, used := m.used[c.Nonce]; used {
+		return ErrUsed
+	}
+	m.used[c.Nonce] = time.Unix(c.Expires, 0)
+	if err := m.saveUsedLocked(); err != nil {
+		delete(m.used, c.Nonce)
+		return fmt.Errorf("failed to record action use: %w", err)
+	}
+	return nil
+}
+
+// loadUsedLocked merges the nonces saved on disk into m.used
+// Caller must hold m.mu
+func (m *Manager) loadUsedLocked() {
+	data, err := os.ReadFile(m.usedPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error("Failed to read used actions", "err", err)
+		}
+		return
+	}
+	var saved map[string]time.Time
+	if err := json.Unmarshal(data, &saved); err != nil {
+		logger.Error("Failed to parse used actions", "err", err)
+		return
+	}
+	for nonce, exp := range saved {
+		m.used[nonce] = exp
+	}
+}
+
+// saveUsedLocked writes m.used to disk atomically
+// Caller must hold m.mu
+func (m *Manager) saveUsedLocked() error {
+	data, err := json.Marshal(m.used)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.usedPath), 0700); err != nil {
+		return err
+	}
+	tmp := m.usedPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.usedPath)
+}
+
+func (m *Manager) sign(body string) string {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c *claims) info() *Info {
+	return &Info{SessionID: c.SessionID, Label: c.Label, Expires: time.Unix(c.Expires, 0)}
+}
@@ -0,0 +1,98 @@
+package actions
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"winterm-bridge/internal/logging"
+)
+
+var logger = logging.For("actions")
+
+// Audit log settings
+const (
+	auditFile    = "action_audit.log"
+	auditMemory  = 200              // Entries kept in memory for the API
+	auditMaxSize = 10 * 1024 * 1024 // Rotate the file to .1 beyond this size
+)
+
+// AuditEntry records one attempt to redeem an action token
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	SessionID string    `json:"session_id,omitempty"`
+	Label     string    `json:"label,omitempty"`
+	Remote    string    `json:"remote"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	OK        bool      `json:"ok"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// auditLog appends entries to a JSON-lines file and keeps the latest in memory
+type auditLog struct {
+	path    string
+	entries []AuditEntry
+	mu      sync.Mutex
+}
+
+func newAuditLog(dir string) *auditLog {
+	return &auditLog{path: filepath.Join(dir, auditFile)}
+}
+
+func (a *auditLog) add(e AuditEntry) {
+	if e.OK {
+		logger.Info("Action sent", "label", e.Label, "session", shortID(e.SessionID), "remote", e.Remote)
+	} else {
+		logger.Warn("Action rejected", "remote", e.Remote, "reason", e.Error)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.entries = append(a.entries, e)
+	if len(a.entries) > auditMemory {
+		a.entries = a.entries[len(a.entries)-auditMemory:]
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(a.path), 0700); err != nil {
+		logger.Error("Failed to write audit log", "err", err)
+		return
+	}
+	if info, err := os.Stat(a.path); err == nil && info.Size() > auditMaxSize {
+		_ = os.Rename(a.path, a.path+".1")
+	}
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		logger.Error("Failed to write audit log", "err", err)
+		return
+	}
+	defer f.Close()
+	f.Write(append(line, '\n'))
+}
+
+// recent returns up to limit entries, newest first
+func (a *auditLog) recent(limit int) []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if limit <= 0 || limit > len(a.entries) {
+		limit = len(a.entries)
+	}
+	out := make([]AuditEntry, 0, limit)
+	for i := len(a.entries) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, a.entries[i])
+	}
+	return out
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"winterm-bridge/internal/actions"
+	"winterm-bridge/internal/metrics"
+)
+
+// SetActionManager enables the reply action endpoints
+func (h *Handler) SetActionManager(m *actions.Manager) {
+	h.actions = m
+}
+
+// actionPage is shown for action links opened in a browser
+// Executing takes a POST so mail scanners that prefetch links cannot consume the token
+var actionPage = template.Must(template.New("action").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width,initial-scale=1"><title>WinTerm 快速回复</title></head>
+<body style="margin:0;padding:24px;background:#f4f4f5;font-family:-apple-system,'Segoe UI',Roboto,'PingFang SC','Microsoft YaHei',sans-serif;color:#1f2937">
+<div style="max-width:420px;margin:0 auto;background:#ffffff;border-radius:8px;padding:20px">
+{{if .Error}}<p style="font-size:16px;color:#d93025;margin:0">{{.Error}}</p>
+{{else if .Done}}<p style="font-size:16px;color:#188038;margin:0">已发送 <code>{{.Info.Label}}</code></p>
+{{else}}<p style="font-size:16px;margin:0 0 16px">向会话发送 <code style="padding:2px 6px;background:#f3f4f6;border-radius:4px">{{.Info.Label}}</code> ?</p>
+<form method="post"><button type="submit" style="padding:8px 20px;border:0;border-radius:6px;background:#2563eb;color:#ffffff;font-size:15px">确认发送</button></form>
+<p style="font-size:12px;color:#6b7280;margin:16px 0 0">链接仅可使用一次，有效期至 {{.Info.Expires.Format "2006-01-02 15:04:05"}}</p>{{end}}
+</div>
+</body>
+</html>
+`))
+
+// HandleAction handles GET/POST /api/actions/{token} - One-click replies from notifications
+// Unauthenticated: the signed, single-use token is the credential
+// GET shows a confirmation page; POST sends the keys (HTML for the form, JSON for webhook callbacks)
+func (h *Handler) HandleAction(w http.ResponseWriter, r *http.Request) {
+	if h.actions == nil {
+		writeError(w, http.StatusNotFound, "actions are disabled")
+		return
+	}
+	token := strings.TrimPrefix(r.URL.Path, "/api/actions/")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	switch r.Method {
+	case http.MethodGet:
+		info, err := h.actions.Inspect(token)
+		renderActionPage(w, actionStatus(err), info, false, err)
+	case http.MethodPost:
+		info, err := h.actions.Execute(token, r.RemoteAddr, r.UserAgent())
+		if errors.Is(err, actions.ErrInvalidToken) {
+			metrics.AuthFailures.With("action").Inc()
+		}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			renderActionPage(w, actionStatus(err), info, err == nil, err)
+			return
+		}
+		if err != nil {
+			writeError(w, actionStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"ok":         true,
+			"session_id": info.SessionID,
+			"label":      info.Label,
+		})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// HandleActionAudit handles GET /api/actions/audit - Recent action attempts
+func (h *Handler) HandleActionAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	entries := []actions.AuditEntry{}
+	if h.actions != nil {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		entries = h.actions.Audit(limit)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+	})
+}
+
+// actionStatus maps token errors to HTTP status codes
+func actionStatus(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, actions.ErrInvalidToken):
+		return http.StatusNotFound
+	case errors.Is(err, actions.ErrExpired), errors.Is(err, actions.ErrUsed):
+		return http.StatusGone
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func renderActionPage(w http.ResponseWriter, status int, info *actions.Info, done bool, err error) {
+	data := map[string]any{"Info": info, "Done": done}
+	switch {
+	case errors.Is(err, actions.ErrInvalidToken):
+		data["Error"] = "链接无效"
+	case errors.Is(err, actions.ErrExpired):
+		data["Error"] = "链接已过期"
+	case errors.Is(err, actions.ErrUsed):
+		data["Error"] = "链接已被使用"
+	case err != nil:
+		data["Error"] = "发送失败: " + err.Error()
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	actionPage.Execute(w, data)
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"winterm-bridge/internal/events"
+)
+
+// eventKeepAlive is how often a comment line is sent to keep idle streams open through proxies
+const eventKeepAlive = 30 * time.Second
+
+// HandleEvents handles GET /api/events - Server-sent event stream of summaries,
+// session lifecycle and client attach/detach events across all sessions
+func (h *Handler) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	ch := h.events.Subscribe()
+	defer h.events.Unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+	w.WriteHeader(http.StatusOK)
+
+	// Send current summaries so the client starts from a consistent snapshot
+	for _, sess := range h.registry.ListAll() {
+		if summary := h.monitorService.GetSummary(sess.ID); summary != nil {
+			writeEvent(w, events.Event{
+				Type:      events.TypeAISummary,
+				SessionID: sess.ID,
+				Data:      summary,
+				Timestamp: summary.Timestamp,
+			})
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(eventKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes a single event in text/event-stream format
+func writeEvent(w http.ResponseWriter, ev events.Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return nil // Skip unencodable events
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+	return err
+}
@@ -3,35 +3,45 @@ package api
 import (
 	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"winterm-bridge/internal/actions"
 	"winterm-bridge/internal/auth"
 	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/events"
+	"winterm-bridge/internal/logging"
+	"winterm-bridge/internal/metrics"
 	"winterm-bridge/internal/monitor"
 	"winterm-bridge/internal/pty"
 	"winterm-bridge/internal/session"
+	"winterm-bridge/internal/tmux"
 )
 
+var logger = logging.For("api")
+
 // Handler handles HTTP REST API requests
 type Handler struct {
 	registry       *session.Registry
 	tokenStore     *auth.AttachmentTokenStore
 	ptyManager     *pty.Manager
 	monitorService *monitor.Service
+	events         *events.Hub
+	actions        *actions.Manager
 }
 
 // NewHandler creates a new HTTP API handler
-func NewHandler(registry *session.Registry, tokenStore *auth.AttachmentTokenStore, ptyManager *pty.Manager, monitorService *monitor.Service) *Handler {
+func NewHandler(registry *session.Registry, tokenStore *auth.AttachmentTokenStore, ptyManager *pty.Manager, monitorService *monitor.Service, hub *events.Hub) *Handler {
 	return &Handler{
 		registry:       registry,
 		tokenStore:     tokenStore,
 		ptyManager:     ptyManager,
 		monitorService: monitorService,
+		events:         hub,
 	}
 }
 
@@ -83,7 +93,8 @@ type AttachResponse struct {
 }
 
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error  string              `json:"error"`
+	Fields []config.FieldError `json:"fields,omitempty"` // Set when a config value was rejected
 }
 
 // Helper functions
@@ -98,6 +109,15 @@ func writeError(w http.ResponseWriter, status int, message string) {
 	writeJSON(w, status, ErrorResponse{Error: message})
 }
 
+// writeValidationError reports the rejected fields of a config update
+func writeValidationError(w http.ResponseWriter, err error) {
+	resp := ErrorResponse{Error: err.Error()}
+	if v, ok := err.(*config.ValidationError); ok {
+		resp.Fields = v.Fields
+	}
+	writeJSON(w, http.StatusBadRequest, resp)
+}
+
 func sessionStateString(state session.SessionState) string {
 	switch state {
 	case session.SessionActive:
@@ -156,6 +176,8 @@ func (h *Handler) HandleAuth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !auth.ValidatePIN(req.PIN) {
+		metrics.AuthFailures.With("pin").Inc()
+		logger.WarnContext(r.Context(), "Invalid PIN", "remote", r.RemoteAddr)
 		writeError(w, http.StatusUnauthorized, "invalid PIN")
 		return
 	}
@@ -166,7 +188,7 @@ func (h *Handler) HandleAuth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("[API] PIN authenticated, token: %s...", token[:8])
+	logger.InfoContext(r.Context(), "PIN authenticated", "remote", r.RemoteAddr)
 	writeJSON(w, http.StatusOK, AuthResponse{
 		Token:     token,
 		ExpiresAt: time.Now().Add(24 * time.Hour), // Token expires in 24 hours
@@ -266,6 +288,76 @@ func (h *Handler) HandleDeleteSession(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// HandleRenameSession handles PATCH /api/sessions/{id} - Change the display title
+func (h *Handler) HandleRenameSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	sessionID := parts[len(parts)-1]
+	if len(parts) < 4 || sessionID == "" {
+		writeError(w, http.StatusBadRequest, "missing session ID")
+		return
+	}
+
+	var req struct {
+		Title string `json:"title"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		writeError(w, http.StatusBadRequest, "title is required")
+		return
+	}
+
+	if err := h.registry.Rename(sessionID, req.Title); err != nil {
+		if err == session.ErrSessionNotFound {
+			writeError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to save title: "+err.Error())
+		return
+	}
+
+	logger.InfoContext(r.Context(), "Session renamed", "session", sessionID[:8], "title", req.Title)
+	writeJSON(w, http.StatusOK, CreateSessionResponse{Session: sessionToInfo(h.registry.Get(sessionID))})
+}
+
+// HandleCaptureSession handles GET /api/sessions/{id}/capture - Current pane content
+// Query: lines (last N non-empty lines, default all visible), ansi=1 to keep color escapes
+func (h *Handler) HandleCaptureSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	sess := h.liveSession(w, r)
+	if sess == nil {
+		return
+	}
+
+	lines, _ := strconv.Atoi(r.URL.Query().Get("lines"))
+	var content string
+	var err error
+	if r.URL.Query().Get("ansi") == "1" {
+		content, err = tmux.CaptureSessionPaneANSI(sess.TmuxName, lines)
+	} else {
+		content, err = tmux.CaptureSessionPane(sess.TmuxName, lines)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"content": content,
+	})
+}
+
 // HandleAttachSession handles POST /api/sessions/{id}/attach - Get attachment token
 func (h *Handler) HandleAttachSession(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -528,9 +620,11 @@ func (h *Handler) HandleAIConfig(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) handleGetAIConfig(w http.ResponseWriter, r *http.Request) {
 	cfg := h.monitorService.GetConfig()
 
-	// Mask API key for security
+	// Mask API key for security; an env:/file: reference only says where the key is
 	maskedKey := ""
-	if cfg.APIKey != "" {
+	if config.IsSecretRef(cfg.APIKey) {
+		maskedKey = cfg.APIKey
+	} else if cfg.APIKey != "" {
 		if len(cfg.APIKey) > 8 {
 			maskedKey = cfg.APIKey[:4] + "****" + cfg.APIKey[len(cfg.APIKey)-4:]
 		} else {
@@ -546,6 +640,18 @@ func (h *Handler) handleGetAIConfig(w http.ResponseWriter, r *http.Request) {
 		"lines":    cfg.Lines,
 		"interval": cfg.Interval,
 		"running":  h.monitorService.IsRunning(),
+
+		"history_size":    cfg.HistorySize,
+		"persist_history": cfg.PersistHistory,
+
+		"prices":       cfg.Prices,
+		"daily_budget": cfg.DailyBudget,
+		"paused":       h.monitorService.IsPaused(),
+
+		"concurrency":       cfg.Concurrency,
+		"max_idle_interval": cfg.MaxIdleInterval,
+
+		"redact_patterns": cfg.RedactPatterns,
 	})
 }
 
@@ -557,6 +663,17 @@ func (h *Handler) handleSetAIConfig(w http.ResponseWriter, r *http.Request) {
 		Model    *string `json:"model"`
 		Lines    *int    `json:"lines"`
 		Interval *int    `json:"interval"`
+
+		HistorySize    *int  `json:"history_size"`
+		PersistHistory *bool `json:"persist_history"`
+
+		Prices      map[string]config.ModelPrice `json:"prices"`
+		DailyBudget *float64                     `json:"daily_budget"`
+
+		Concurrency     *int `json:"concurrency"`
+		MaxIdleInterval *int `json:"max_idle_interval"`
+
+		RedactPatterns []string `json:"redact_patterns"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -579,32 +696,46 @@ func (h *Handler) handleSetAIConfig(w http.ResponseWriter, r *http.Request) {
 	if req.Model != nil && *req.Model != "" {
 		cfg.Model = *req.Model
 	}
-	if req.Lines != nil && *req.Lines > 0 {
+	if req.Lines != nil {
 		cfg.Lines = *req.Lines
 	}
-	if req.Interval != nil && *req.Interval >= 5 {
+	if req.Interval != nil {
 		cfg.Interval = *req.Interval
 	}
-
-	// Save to config file
-	aiCfg := &config.AIMonitorConfig{
-		Enabled:  cfg.Enabled,
-		Endpoint: cfg.Endpoint,
-		APIKey:   cfg.APIKey,
-		Model:    cfg.Model,
-		Lines:    cfg.Lines,
-		Interval: cfg.Interval,
+	if req.HistorySize != nil {
+		cfg.HistorySize = *req.HistorySize
 	}
-	if err := config.SaveAIMonitorConfig(aiCfg); err != nil {
-		log.Printf("[API] Failed to save AI config: %v", err)
-		writeError(w, http.StatusInternalServerError, "failed to save config")
+	if req.PersistHistory != nil {
+		cfg.PersistHistory = *req.PersistHistory
+	}
+	if req.Prices != nil {
+		cfg.Prices = req.Prices
+	}
+	if req.DailyBudget != nil {
+		cfg.DailyBudget = *req.DailyBudget
+	}
+	if req.Concurrency != nil {
+		cfg.Concurrency = *req.Concurrency
+	}
+	if req.MaxIdleInterval != nil {
+		cfg.MaxIdleInterval = *req.MaxIdleInterval
+	}
+	if req.RedactPatterns != nil {
+		cfg.RedactPatterns = req.RedactPatterns
+	}
+	if err := cfg.Validate(); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
-	// Update monitor service
-	h.monitorService.UpdateConfig(cfg)
+	// Saving also applies it: the monitor service subscribes to config changes
+	if err := config.SaveAIMonitorConfig(&cfg); err != nil {
+		logger.ErrorContext(r.Context(), "Failed to save AI config", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to save config")
+		return
+	}
 
-	log.Printf("[API] AI monitor config updated (enabled=%v, model=%s)", cfg.Enabled, cfg.Model)
+	logger.InfoContext(r.Context(), "AI monitor config updated", "enabled", cfg.Enabled, "model", cfg.Model)
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"ok":      true,
 		"running": h.monitorService.IsRunning(),
@@ -662,6 +793,52 @@ func (h *Handler) HandleAITest(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleAIPreview handles GET /api/sessions/{id}/ai-preview - Dry run showing the redacted
+// content that would be sent to the LLM for a session
+func (h *Handler) HandleAIPreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// Extract session ID from path: /api/sessions/{id}/ai-preview
+	path := r.URL.Path
+	parts := strings.Split(path, "/")
+	if len(parts) < 5 {
+		writeError(w, http.StatusBadRequest, "missing session ID")
+		return
+	}
+	sessionID := parts[len(parts)-2]
+
+	if sessionID == "" {
+		writeError(w, http.StatusBadRequest, "missing session ID")
+		return
+	}
+
+	if h.registry.Get(sessionID) == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+
+	preview, err := h.monitorService.Preview(sessionID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to capture session: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, preview)
+}
+
+// HandleAIStats handles GET /api/ai/stats - LLM request, token, latency and cost statistics
+func (h *Handler) HandleAIStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.monitorService.GetStats())
+}
+
 // HandleAISummaries handles GET /api/ai/summaries - Get all session AI summaries
 func (h *Handler) HandleAISummaries(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -696,6 +873,55 @@ func (h *Handler) HandleAISummaries(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HistoryEntryInfo is a summary transition with the time spent in that state
+type HistoryEntryInfo struct {
+	monitor.HistoryEntry
+	Duration int64 `json:"duration"` // seconds until the next transition (or until now for the latest)
+}
+
+// HandleAISummaryHistory handles GET /api/ai/summaries/{id}/history - Get a session's summary timeline
+func (h *Handler) HandleAISummaryHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// Extract session ID from path: /api/ai/summaries/{id}/history
+	path := r.URL.Path
+	parts := strings.Split(path, "/")
+	// Expected: ["", "api", "ai", "summaries", "{id}", "history"]
+	if len(parts) < 6 || parts[len(parts)-1] != "history" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	sessionID := parts[len(parts)-2]
+
+	if sessionID == "" {
+		writeError(w, http.StatusBadRequest, "missing session ID")
+		return
+	}
+
+	entries := h.monitorService.GetHistory(sessionID)
+	now := time.Now().Unix()
+
+	infos := make([]HistoryEntryInfo, 0, len(entries))
+	for i, e := range entries {
+		end := now
+		if i+1 < len(entries) {
+			end = entries[i+1].Timestamp
+		}
+		infos = append(infos, HistoryEntryInfo{
+			HistoryEntry: e,
+			Duration:     end - e.Timestamp,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"session_id": sessionID,
+		"history":    infos,
+	})
+}
+
 // HandleEmailConfig handles GET/POST /api/email/config - Email notification configuration
 func (h *Handler) HandleEmailConfig(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -718,27 +944,37 @@ func (h *Handler) handleGetEmailConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"enabled":      cfg.Enabled,
-		"smtp_host":    cfg.SMTPHost,
-		"smtp_port":    cfg.SMTPPort,
-		"username":     cfg.Username,
-		"password":     maskedPassword,
-		"from_address": cfg.FromAddress,
-		"to_address":   cfg.ToAddress,
-		"notify_delay": cfg.NotifyDelay,
+		"enabled":       cfg.Enabled,
+		"smtp_host":     cfg.SMTPHost,
+		"smtp_port":     cfg.SMTPPort,
+		"username":      cfg.Username,
+		"password":      maskedPassword,
+		"from_address":  cfg.FromAddress,
+		"to_address":    cfg.ToAddress,
+		"notify_delay":  cfg.NotifyDelay,
+		"public_url":    cfg.PublicURL,
+		"excerpt_lines": cfg.ExcerptLines,
+		"cc_address":    cfg.CCAddress,
+		"auth_method":   cfg.AuthMethod,
+		"timeout":       cfg.Timeout,
 	})
 }
 
 func (h *Handler) handleSetEmailConfig(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Enabled     *bool   `json:"enabled"`
-		SMTPHost    *string `json:"smtp_host"`
-		SMTPPort    *int    `json:"smtp_port"`
-		Username    *string `json:"username"`
-		Password    *string `json:"password"`
-		FromAddress *string `json:"from_address"`
-		ToAddress   *string `json:"to_address"`
-		NotifyDelay *int    `json:"notify_delay"`
+		Enabled      *bool   `json:"enabled"`
+		SMTPHost     *string `json:"smtp_host"`
+		SMTPPort     *int    `json:"smtp_port"`
+		Username     *string `json:"username"`
+		Password     *string `json:"password"`
+		FromAddress  *string `json:"from_address"`
+		ToAddress    *string `json:"to_address"`
+		NotifyDelay  *int    `json:"notify_delay"`
+		PublicURL    *string `json:"public_url"`
+		ExcerptLines *int    `json:"excerpt_lines"`
+		CCAddress    *string `json:"cc_address"`
+		AuthMethod   *string `json:"auth_method"`
+		Timeout      *int    `json:"timeout"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -776,23 +1012,52 @@ func (h *Handler) handleSetEmailConfig(w http.ResponseWriter, r *http.Request) {
 	if req.NotifyDelay != nil {
 		cfg.NotifyDelay = *req.NotifyDelay
 	}
+	if req.PublicURL != nil {
+		cfg.PublicURL = *req.PublicURL
+	}
+	if req.ExcerptLines != nil {
+		cfg.ExcerptLines = *req.ExcerptLines
+	}
+	if req.CCAddress != nil {
+		cfg.CCAddress = *req.CCAddress
+	}
+	if req.AuthMethod != nil {
+		cfg.AuthMethod = strings.ToLower(*req.AuthMethod)
+	}
+	if req.Timeout != nil {
+		cfg.Timeout = *req.Timeout
+	}
 
-	// Save to config file
+	if err := cfg.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	// Saving also applies it: the monitor service subscribes to config changes
 	if err := config.SaveEmailConfig(cfg); err != nil {
-		log.Printf("[API] Failed to save email config: %v", err)
+		logger.ErrorContext(r.Context(), "Failed to save email config", "err", err)
 		writeError(w, http.StatusInternalServerError, "failed to save config")
 		return
 	}
 
-	// Update monitor service
-	h.monitorService.UpdateEmailConfig(cfg)
-
-	log.Printf("[API] Email config updated (enabled=%v, host=%s)", cfg.Enabled, cfg.SMTPHost)
+	logger.InfoContext(r.Context(), "Email config updated", "enabled", cfg.Enabled, "host", cfg.SMTPHost)
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"ok": true,
 	})
 }
 
+// HandleEmailOutbox handles GET /api/email/outbox - Emails waiting for delivery or retry
+func (h *Handler) HandleEmailOutbox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"items": h.monitorService.GetEmailOutbox(),
+	})
+}
+
 // HandleEmailTest handles POST /api/email/test - Send test email
 func (h *Handler) HandleEmailTest(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -842,7 +1107,7 @@ func (h *Handler) HandleSessionNotify(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusInternalServerError, "failed to enable notification: "+err.Error())
 			return
 		}
-		log.Printf("[API] Session %s notification enabled", sessionID[:8])
+		logger.InfoContext(r.Context(), "Session notification enabled", "session", sessionID[:8])
 		w.WriteHeader(http.StatusNoContent)
 
 	case http.MethodDelete:
@@ -850,7 +1115,7 @@ func (h *Handler) HandleSessionNotify(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusInternalServerError, "failed to disable notification: "+err.Error())
 			return
 		}
-		log.Printf("[API] Session %s notification disabled", sessionID[:8])
+		logger.InfoContext(r.Context(), "Session notification disabled", "session", sessionID[:8])
 		w.WriteHeader(http.StatusNoContent)
 
 	default:
@@ -858,9 +1123,19 @@ func (h *Handler) HandleSessionNotify(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// HandleSessionSettings handles GET /api/sessions/{id}/settings - Get session settings
+// SessionMonitorSettings is the per-session AI monitor section of session settings
+type SessionMonitorSettings struct {
+	Enabled  bool   `json:"enabled"`
+	Lines    int    `json:"lines"`    // 0 = use global setting
+	Interval int    `json:"interval"` // 0 = use global setting
+	Model    string `json:"model"`    // empty = use global setting
+	// NeverSend opts the session out of AI analysis entirely (content is never captured for the LLM)
+	NeverSend bool `json:"never_send"`
+}
+
+// HandleSessionSettings handles GET/PUT /api/sessions/{id}/settings - Session settings
 func (h *Handler) HandleSessionSettings(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
@@ -886,10 +1161,92 @@ func (h *Handler) HandleSessionSettings(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if r.Method == http.MethodPut {
+		if !h.updateSessionSettings(w, r, sessionID) {
+			return
+		}
+	}
+
 	notifyEnabled := config.GetSessionNotifyEnabled(sessionID)
 
+	monitorSettings := SessionMonitorSettings{Enabled: true}
+	if ms := config.GetSessionMonitorSettings(sessionID); ms != nil {
+		monitorSettings = SessionMonitorSettings{
+			Enabled:  !ms.Disabled,
+			Lines:    ms.Lines,
+			Interval: ms.Interval,
+			Model:    ms.Model,
+
+			NeverSend: ms.NeverSend,
+		}
+	}
+
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"notify_enabled": notifyEnabled,
 		"is_persistent":  sess.IsPersistent,
+		"monitor":        monitorSettings,
 	})
 }
+
+// updateSessionSettings applies a PUT /api/sessions/{id}/settings body
+// Returns false if an error response has already been written
+func (h *Handler) updateSessionSettings(w http.ResponseWriter, r *http.Request, sessionID string) bool {
+	var req struct {
+		NotifyEnabled *bool `json:"notify_enabled"`
+		Monitor       *struct {
+			Enabled  *bool   `json:"enabled"`
+			Lines    *int    `json:"lines"`
+			Interval *int    `json:"interval"`
+			Model    *string `json:"model"`
+
+			NeverSend *bool `json:"never_send"`
+		} `json:"monitor"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return false
+	}
+
+	if req.Monitor != nil {
+		ms := config.SessionMonitorSettings{SessionID: sessionID}
+		if existing := config.GetSessionMonitorSettings(sessionID); existing != nil {
+			ms = *existing
+		}
+
+		if req.Monitor.Enabled != nil {
+			ms.Disabled = !*req.Monitor.Enabled
+		}
+		if req.Monitor.Lines != nil {
+			ms.Lines = *req.Monitor.Lines
+		}
+		if req.Monitor.Interval != nil {
+			ms.Interval = *req.Monitor.Interval
+		}
+		if req.Monitor.Model != nil {
+			ms.Model = strings.TrimSpace(*req.Monitor.Model)
+		}
+		if req.Monitor.NeverSend != nil {
+			ms.NeverSend = *req.Monitor.NeverSend
+		}
+		if err := ms.Validate(); err != nil {
+			writeValidationError(w, err)
+			return false
+		}
+
+		if err := config.SetSessionMonitorSettings(ms); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to save settings: "+err.Error())
+			return false
+		}
+	}
+
+	if req.NotifyEnabled != nil {
+		if err := config.SetSessionNotifyEnabled(sessionID, *req.NotifyEnabled); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to save settings: "+err.Error())
+			return false
+		}
+	}
+
+	logger.InfoContext(r.Context(), "Session settings updated", "session", sessionID[:8])
+	return true
+}
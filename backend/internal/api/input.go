@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"winterm-bridge/internal/session"
+	"winterm-bridge/internal/tmux"
+)
+
+// maxExecTimeout caps how long an exec request may hold the connection
+const maxExecTimeout = 10 * time.Minute
+
+// InputRequest is the body of POST /api/sessions/{id}/input
+// Parts are sent in order: text, then hex, then keys
+type InputRequest struct {
+	Text string   `json:"text,omitempty"` // Typed literally
+	Hex  string   `json:"hex,omitempty"`  // Raw bytes, e.g. "1b5b41" (spaces allowed)
+	Keys []string `json:"keys,omitempty"` // tmux key names: Enter, C-c, Up, F5, ...
+}
+
+// ExecRequest is the body of POST /api/sessions/{id}/exec
+type ExecRequest struct {
+	Command string `json:"command"`
+	Timeout int    `json:"timeout,omitempty"` // seconds, default 30, max 600
+	// Prompt waits for this regex on the last line instead of using exit-status markers
+	// Set to "default" for a generic shell prompt pattern
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// HandleSessionInput handles POST /api/sessions/{id}/input - Send keystrokes without a WebSocket
+func (h *Handler) HandleSessionInput(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	sess := h.liveSession(w, r)
+	if sess == nil {
+		return
+	}
+
+	var req InputRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	raw, err := hex.DecodeString(strings.ReplaceAll(req.Hex, " ", ""))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "hex must be pairs of hex digits")
+		return
+	}
+	for _, k := range req.Keys {
+		if !tmux.ValidKeyName(k) {
+			writeError(w, http.StatusBadRequest, "unknown key: "+k)
+			return
+		}
+	}
+	if req.Text == "" && len(raw) == 0 && len(req.Keys) == 0 {
+		writeError(w, http.StatusBadRequest, "text, hex or keys is required")
+		return
+	}
+
+	if err := tmux.SendKeysToSession(sess.TmuxName, []byte(req.Text)); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := tmux.SendKeysToSession(sess.TmuxName, raw); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := tmux.SendNamedKeys(sess.TmuxName, req.Keys...); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSessionExec handles POST /api/sessions/{id}/exec - Run a command and wait for its output
+func (h *Handler) HandleSessionExec(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	sess := h.liveSession(w, r)
+	if sess == nil {
+		return
+	}
+
+	var req ExecRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Command) == "" {
+		writeError(w, http.StatusBadRequest, "command is required")
+		return
+	}
+
+	opts := tmux.ExecOptions{Timeout: time.Duration(req.Timeout) * time.Second}
+	if opts.Timeout > maxExecTimeout {
+		opts.Timeout = maxExecTimeout
+	}
+	if req.Prompt != "" {
+		pattern := req.Prompt
+		if pattern == "default" {
+			pattern = tmux.DefaultPromptPattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid prompt pattern: "+err.Error())
+			return
+		}
+		opts.Prompt = re
+	}
+
+	result, err := tmux.RunCommand(r.Context(), sess.TmuxName, req.Command, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if result.TimedOut {
+		logger.WarnContext(r.Context(), "Exec timed out", "session", sess.ID[:8], "duration_ms", result.Duration)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// liveSession resolves /api/sessions/{id}/... to a session with a running tmux backend
+// Writes the error response and returns nil if there is none
+func (h *Handler) liveSession(w http.ResponseWriter, r *http.Request) *session.Session {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 5 || parts[len(parts)-2] == "" {
+		writeError(w, http.StatusBadRequest, "missing session ID")
+		return nil
+	}
+	sess := h.registry.Get(parts[len(parts)-2])
+	if sess == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return nil
+	}
+	if sess.IsGhost || !tmux.SessionExists(sess.TmuxName) {
+		writeError(w, http.StatusConflict, "session is not running; attach to revive it first")
+		return nil
+	}
+	return sess
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"winterm-bridge/internal/auth"
+	"winterm-bridge/internal/metrics"
 )
 
 type contextKey string
@@ -18,26 +19,26 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		// Extract token from Authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			writeError(w, http.StatusUnauthorized, "missing authorization header")
+			unauthorized(w, "missing authorization header")
 			return
 		}
 
 		// Check for Bearer token
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			writeError(w, http.StatusUnauthorized, "invalid authorization header format")
+			unauthorized(w, "invalid authorization header format")
 			return
 		}
 
 		token := parts[1]
 		if token == "" {
-			writeError(w, http.StatusUnauthorized, "missing token")
+			unauthorized(w, "missing token")
 			return
 		}
 
 		// Validate token
 		if !auth.ValidateToken(token) {
-			writeError(w, http.StatusUnauthorized, "invalid token")
+			unauthorized(w, "invalid token")
 			return
 		}
 
@@ -46,3 +47,22 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		next(w, r.WithContext(ctx))
 	}
 }
+
+// unauthorized rejects a request and counts the failure
+func unauthorized(w http.ResponseWriter, message string) {
+	metrics.AuthFailures.With("token").Inc()
+	writeError(w, http.StatusUnauthorized, message)
+}
+
+// StreamAuthMiddleware is like AuthMiddleware but also accepts the token as a
+// ?token= query parameter, since browser EventSource cannot set headers
+func StreamAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			if token := r.URL.Query().Get("token"); token != "" {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		AuthMiddleware(next)(w, r)
+	}
+}
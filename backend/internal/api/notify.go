@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/notify"
+)
+
+// maskedSecret is returned in place of stored secrets and accepted back as "unchanged"
+const maskedSecret = "****"
+
+// HandleNotifyChannels handles GET/POST /api/notify/channels - Notification channel configuration
+func (h *Handler) HandleNotifyChannels(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		channels := h.monitorService.GetNotifyChannels()
+		for i := range channels {
+			channels[i] = maskChannel(channels[i])
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"channels": channels,
+		})
+	case http.MethodPost:
+		h.handleSetNotifyChannels(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *Handler) handleSetNotifyChannels(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Channels []config.NotifyChannelConfig `json:"channels"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	// Keep stored secrets for channels submitted with masked values
+	existing := make(map[string]config.NotifyChannelConfig)
+	for _, c := range h.monitorService.GetNotifyChannels() {
+		existing[c.Name] = c
+	}
+	for i, c := range req.Channels {
+		old, ok := existing[c.Name]
+		if !ok {
+			continue
+		}
+		if c.Secret == maskedSecret {
+			req.Channels[i].Secret = old.Secret
+		}
+		if c.Token == maskedSecret {
+			req.Channels[i].Token = old.Token
+		}
+	}
+
+	if err := h.monitorService.UpdateNotifyChannels(req.Channels); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := config.SaveNotifyChannels(req.Channels); err != nil {
+		logger.ErrorContext(r.Context(), "Failed to save notification channels", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to save config")
+		return
+	}
+
+	logger.InfoContext(r.Context(), "Notification channels updated", "count", len(req.Channels))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ok": true,
+	})
+}
+
+// HandleNotifyRules handles GET/POST /api/notify/rules - Notification routing rules
+func (h *Handler) HandleNotifyRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"rules": h.monitorService.GetNotifyRules(),
+		})
+	case http.MethodPost:
+		h.handleSetNotifyRules(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *Handler) handleSetNotifyRules(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Rules []config.NotifyRule `json:"rules"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	// Rules may only reference channels that exist
+	known := map[string]bool{notify.EmailChannel: true, notify.WebPushChannel: true}
+	for _, c := range h.monitorService.GetNotifyChannels() {
+		known[c.Name] = true
+	}
+	for _, rule := range req.Rules {
+		for _, name := range append(append([]string{}, rule.Channels...), rule.EscalateTo...) {
+			if !known[name] {
+				writeError(w, http.StatusBadRequest, "rule "+rule.Name+": unknown channel "+name)
+				return
+			}
+		}
+	}
+
+	if err := h.monitorService.UpdateNotifyRules(req.Rules); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := config.SaveNotifyRules(req.Rules); err != nil {
+		logger.ErrorContext(r.Context(), "Failed to save notification rules", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to save config")
+		return
+	}
+
+	logger.InfoContext(r.Context(), "Notification rules updated", "count", len(req.Rules))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ok": true,
+	})
+}
+
+// HandleNotifyTest handles POST /api/notify/test - Send a test notification through one channel
+func (h *Handler) HandleNotifyTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req struct {
+		Channel string `json:"channel"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Channel == "" {
+		writeError(w, http.StatusBadRequest, "channel is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	if err := h.monitorService.TestNotifyChannel(ctx, req.Channel); err != nil {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"ok":    false,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ok": true,
+	})
+}
+
+// maskChannel hides secrets in a channel configuration
+func maskChannel(c config.NotifyChannelConfig) config.NotifyChannelConfig {
+	if c.Secret != "" {
+		c.Secret = maskedSecret
+	}
+	if c.Token != "" {
+		c.Token = maskedSecret
+	}
+	return c
+}
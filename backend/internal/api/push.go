@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"winterm-bridge/internal/config"
+)
+
+// PushSubscriptionInfo describes a registered browser without its encryption keys
+type PushSubscriptionInfo struct {
+	Endpoint  string    `json:"endpoint"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Created   time.Time `json:"created"`
+}
+
+// HandlePushVAPIDKey handles GET /api/push/vapid-key - Public key for PushManager.subscribe
+func (h *Handler) HandlePushVAPIDKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	key := h.monitorService.WebPushPublicKey()
+	if key == "" {
+		writeError(w, http.StatusServiceUnavailable, "web push is not available")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"public_key": key,
+	})
+}
+
+// HandlePushSubscriptions handles GET/POST/DELETE /api/push/subscriptions - Browser push registrations
+func (h *Handler) HandlePushSubscriptions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		var subs []PushSubscriptionInfo
+		if cfg := config.GetWebPushConfig(); cfg != nil {
+			for _, s := range cfg.Subscriptions {
+				subs = append(subs, PushSubscriptionInfo{
+					Endpoint:  s.Endpoint,
+					UserAgent: s.UserAgent,
+					Created:   s.Created,
+				})
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"subscriptions": subs,
+		})
+	case http.MethodPost:
+		h.handleAddPushSubscription(w, r)
+	case http.MethodDelete:
+		h.handleRemovePushSubscription(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleAddPushSubscription accepts the JSON form of a browser PushSubscription
+func (h *Handler) handleAddPushSubscription(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Endpoint string `json:"endpoint"`
+		Keys     struct {
+			P256dh string `json:"p256dh"`
+			Auth   string `json:"auth"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	u, err := url.Parse(req.Endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		writeError(w, http.StatusBadRequest, "endpoint must be an http(s) URL")
+		return
+	}
+	if req.Keys.P256dh == "" || req.Keys.Auth == "" {
+		writeError(w, http.StatusBadRequest, "keys.p256dh and keys.auth are required")
+		return
+	}
+
+	sub := config.PushSubscription{
+		Endpoint:  req.Endpoint,
+		P256dh:    req.Keys.P256dh,
+		Auth:      req.Keys.Auth,
+		UserAgent: r.UserAgent(),
+		Created:   time.Now(),
+	}
+	if err := config.SavePushSubscription(sub); err != nil {
+		logger.ErrorContext(r.Context(), "Failed to save push subscription", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to save config")
+		return
+	}
+
+	logger.InfoContext(r.Context(), "Push subscription added", "host", u.Host)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ok": true,
+	})
+}
+
+func (h *Handler) handleRemovePushSubscription(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Endpoint string `json:"endpoint"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Endpoint == "" {
+		writeError(w, http.StatusBadRequest, "endpoint is required")
+		return
+	}
+
+	if err := config.RemovePushSubscription(req.Endpoint); err != nil {
+		logger.ErrorContext(r.Context(), "Failed to remove push subscription", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to save config")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ok": true,
+	})
+}
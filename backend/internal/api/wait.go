@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"winterm-bridge/internal/tmux"
+)
+
+// Wait polling
+const (
+	waitPollInterval = 500 * time.Millisecond
+	maxWaitOutput    = 64 * 1024 // Bytes of output returned, from the end
+)
+
+// WaitRequest is the body of POST /api/sessions/{id}/wait
+// The call returns as soon as any given condition is met, or at the timeout
+type WaitRequest struct {
+	Pattern  string `json:"pattern,omitempty"`  // Regex matched against output printed since the call
+	Idle     int    `json:"idle,omitempty"`     // seconds without any output or screen change
+	Tag      string `json:"tag,omitempty"`      // AI monitor status tag, e.g. 完毕 or 需输入, seen after the call
+	Timeout  int    `json:"timeout,omitempty"`  // seconds, default 30, max 600
+	Lookback int    `json:"lookback,omitempty"` // Also search this many lines printed before the call
+}
+
+// WaitResponse describes which condition ended the wait
+type WaitResponse struct {
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason"` // pattern, idle, tag or timeout
+	// Pattern match position from the start of Output: 0-based line and column, and byte offset
+	Match  string   `json:"match,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+	Line   int      `json:"line"`
+	Column int      `json:"column"`
+	Offset int      `json:"offset"`
+	Tag    string   `json:"tag,omitempty"` // Current AI status tag, if any
+	Output string   `json:"output"`        // Text printed since the call (plus lookback)
+	// Truncated means Output was cut to its last 64 KB; positions still refer to the full text
+	Truncated bool  `json:"truncated,omitempty"`
+	Duration  int64 `json:"duration_ms"`
+}
+
+// HandleSessionWait handles POST /api/sessions/{id}/wait - Long-poll until output, idleness or a status tag
+func (h *Handler) HandleSessionWait(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	sess := h.liveSession(w, r)
+	if sess == nil {
+		return
+	}
+
+	var req WaitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Pattern == "" && req.Idle <= 0 && req.Tag == "" {
+		writeError(w, http.StatusBadRequest, "pattern, idle or tag is required")
+		return
+	}
+	var pattern *regexp.Regexp
+	if req.Pattern != "" {
+		re, err := regexp.Compile(req.Pattern)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid pattern: "+err.Error())
+			return
+		}
+		pattern = re
+	}
+	if req.Tag != "" && !h.monitorService.GetConfig().Enabled {
+		writeError(w, http.StatusBadRequest, "waiting for a tag requires the AI monitor to be enabled")
+		return
+	}
+
+	timeout := tmux.DefaultExecTimeout
+	if req.Timeout > 0 {
+		timeout = time.Duration(req.Timeout) * time.Second
+	}
+	if timeout > maxExecTimeout {
+		timeout = maxExecTimeout
+	}
+	idle := time.Duration(req.Idle) * time.Second
+
+	watcher, err := tmux.NewOutputWatcher(sess.TmuxName, req.Lookback)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Only a summary made after the call counts for tag, not one left from the previous command
+	// Summaries of a session are at least the monitor interval apart, so seconds tell them apart
+	var seenSummary int64
+	if summary := h.monitorService.GetSummary(sess.ID); summary != nil {
+		seenSummary = summary.Timestamp
+	}
+
+	start := time.Now()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	var output, screen string
+	lastChange := start
+	for {
+		out, scr, err := watcher.Poll()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if out != output || scr != screen {
+			output, screen = out, scr
+			lastChange = time.Now()
+		}
+
+		resp := WaitResponse{Output: output}
+		freshTag := false
+		if summary := h.monitorService.GetSummary(sess.ID); summary != nil {
+			resp.Tag = summary.Tag
+			freshTag = summary.Timestamp != seenSummary
+		}
+
+		switch {
+		case pattern != nil && pattern.MatchString(output):
+			m := pattern.FindStringSubmatchIndex(output)
+			resp.Reason = "pattern"
+			resp.Match = output[m[0]:m[1]]
+			for i := 2; i < len(m); i += 2 {
+				if m[i] >= 0 {
+					resp.Groups = append(resp.Groups, output[m[i]:m[i+1]])
+				} else {
+					resp.Groups = append(resp.Groups, "")
+				}
+			}
+			resp.Offset = m[0]
+			resp.Line = strings.Count(output[:m[0]], "\n")
+			resp.Column = utf8.RuneCountInString(output[strings.LastIndex(output[:m[0]], "\n")+1 : m[0]])
+		case req.Tag != "" && freshTag && resp.Tag == req.Tag:
+			resp.Reason = "tag"
+		case idle > 0 && time.Since(lastChange) >= idle:
+			resp.Reason = "idle"
+		}
+
+		if resp.Reason != "" {
+			resp.Matched = true
+			writeWaitResponse(w, resp, start)
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline.C:
+			resp.Reason = "timeout"
+			writeWaitResponse(w, resp, start)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeWaitResponse trims the output to its tail and writes the result
+func writeWaitResponse(w http.ResponseWriter, resp WaitResponse, start time.Time) {
+	if len(resp.Output) > maxWaitOutput {
+		cut := len(resp.Output) - maxWaitOutput
+		for cut < len(resp.Output) && !utf8.RuneStart(resp.Output[cut]) {
+			cut++
+		}
+		resp.Output = resp.Output[cut:]
+		resp.Truncated = true
+	}
+	resp.Duration = time.Since(start).Milliseconds()
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -1,20 +1,16 @@
 package config
 
 import (
-	"encoding/json"
 	"os"
 	"path/filepath"
-	"sync"
 	"time"
 )
 
-// configMu protects concurrent access to the config file
-var configMu sync.Mutex
-
 // PersistentSession represents a session saved for persistence across restarts
 type PersistentSession struct {
 	ID         string    `json:"id"`
 	Title      string    `json:"title"`
+	TmuxName   string    `json:"tmux_name,omitempty"` // Kept apart from Title since a rename keeps the tmux name; older files derive it from Title
 	WorkingDir string    `json:"working_dir"`
 	CreatedAt  time.Time `json:"created_at"`
 }
@@ -27,6 +23,23 @@ type AIMonitorConfig struct {
 	Model    string `json:"model"`
 	Lines    int    `json:"lines"`
 	Interval int    `json:"interval"` // seconds
+
+	HistorySize    int  `json:"history_size,omitempty"`    // max summary transitions kept per session
+	PersistHistory bool `json:"persist_history,omitempty"` // save summary history across restarts
+
+	Prices      map[string]ModelPrice `json:"prices,omitempty"`       // per-model prices for cost estimates
+	DailyBudget float64               `json:"daily_budget,omitempty"` // pause monitoring when today's estimated cost reaches this (0 = unlimited)
+
+	Concurrency     int `json:"concurrency,omitempty"`       // sessions analyzed in parallel
+	MaxIdleInterval int `json:"max_idle_interval,omitempty"` // seconds; upper bound for idle backoff
+
+	RedactPatterns []string `json:"redact_patterns,omitempty"` // extra regexes redacted before content is sent to the LLM
+}
+
+// ModelPrice is the price per 1000 tokens for a model, in any consistent currency
+type ModelPrice struct {
+	PromptPer1K     float64 `json:"prompt_per_1k"`
+	CompletionPer1K float64 `json:"completion_per_1k"`
 }
 
 // EmailConfig holds the email notification configuration
@@ -37,8 +50,71 @@ type EmailConfig struct {
 	Username    string `json:"username"`
 	Password    string `json:"password"`
 	FromAddress string `json:"from_address"`
-	ToAddress   string `json:"to_address"`
+	ToAddress   string `json:"to_address"`   // Comma-separated
 	NotifyDelay int    `json:"notify_delay"` // seconds to wait before sending notification (default 60)
+	// Delivery
+	CCAddress  string `json:"cc_address,omitempty"`  // Comma-separated
+	AuthMethod string `json:"auth_method,omitempty"` // plain (default), login, cram-md5, none
+	Timeout    int    `json:"timeout,omitempty"`     // SMTP connection timeout in seconds (default 30)
+	// Rich notification content
+	PublicURL    string `json:"public_url,omitempty"`    // Base URL of the web UI for deep links (empty = no link)
+	ExcerptLines int    `json:"excerpt_lines,omitempty"` // Terminal lines included in emails (0 = default 20, <0 = none)
+}
+
+// NotifyChannelConfig holds the configuration of one notification channel
+// Type is one of webhook, slack, discord, feishu, dingtalk, wecom, ntfy, gotify, command
+type NotifyChannelConfig struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Enabled  bool              `json:"enabled"`
+	URL      string            `json:"url,omitempty"`      // Webhook / server URL
+	Secret   string            `json:"secret,omitempty"`   // HMAC signing secret (webhook, feishu, dingtalk)
+	Token    string            `json:"token,omitempty"`    // Access token (ntfy, gotify)
+	Topic    string            `json:"topic,omitempty"`    // ntfy topic
+	Priority int               `json:"priority,omitempty"` // ntfy/gotify priority
+	Headers  map[string]string `json:"headers,omitempty"`  // Extra HTTP headers (webhook)
+	Command  string            `json:"command,omitempty"`  // Executable (command)
+	Args     []string          `json:"args,omitempty"`     // Arguments (command)
+	Timeout  int               `json:"timeout,omitempty"`  // Seconds (default 10)
+	Actions  bool              `json:"actions,omitempty"`  // Include reply links (ntfy only; off since topics are often public)
+}
+
+// WebPushConfig holds the VAPID keypair and browser push subscriptions
+type WebPushConfig struct {
+	VAPIDPublicKey  string             `json:"vapid_public_key"`  // base64url uncompressed P-256 point
+	VAPIDPrivateKey string             `json:"vapid_private_key"` // base64url P-256 scalar
+	Subject         string             `json:"subject,omitempty"` // mailto: or https: contact for push services
+	Subscriptions   []PushSubscription `json:"subscriptions,omitempty"`
+}
+
+// PushSubscription is a browser PushSubscription registered for notifications
+type PushSubscription struct {
+	Endpoint  string    `json:"endpoint"`
+	P256dh    string    `json:"p256dh"` // base64url client public key
+	Auth      string    `json:"auth"`   // base64url client auth secret
+	UserAgent string    `json:"user_agent,omitempty"`
+	Created   time.Time `json:"created"`
+}
+
+// NotifyRule routes matching session states to notification channels
+// Rules are evaluated independently; every matching rule fires
+type NotifyRule struct {
+	Name           string      `json:"name"`
+	Enabled        bool        `json:"enabled"`
+	Sessions       string      `json:"sessions,omitempty"`        // Glob on session title or tmux name (empty = all)
+	Tags           []string    `json:"tags,omitempty"`            // Status tag globs (empty = all notifiable tags)
+	Channels       []string    `json:"channels,omitempty"`        // Channel names (empty = all enabled channels)
+	Delay          int         `json:"delay,omitempty"`           // Seconds the state must persist (0 = email notify_delay)
+	QuietHours     *QuietHours `json:"quiet_hours,omitempty"`     // Hold notifications during this window
+	RepeatInterval int         `json:"repeat_interval,omitempty"` // Seconds between reminders while the state persists (0 = once)
+	EscalateAfter  int         `json:"escalate_after,omitempty"`  // Minutes without a client attaching before escalating (0 = never)
+	EscalateTo     []string    `json:"escalate_to,omitempty"`     // Channel names for escalation
+}
+
+// QuietHours is a daily local-time window in "HH:MM" format; Start > End wraps past midnight
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
 }
 
 // SessionNotifySettings holds per-session notification settings
@@ -47,9 +123,33 @@ type SessionNotifySettings struct {
 	NotifyEnabled bool   `json:"notify_enabled"`
 }
 
+// SessionMonitorSettings holds per-session AI monitor overrides
+// Zero values mean "use the global AI monitor configuration"
+type SessionMonitorSettings struct {
+	SessionID string `json:"session_id"`
+	Disabled  bool   `json:"disabled,omitempty"`   // Skip AI analysis for this session
+	Lines     int    `json:"lines,omitempty"`      // Custom capture line count
+	Interval  int    `json:"interval,omitempty"`   // Custom analysis interval (seconds)
+	Model     string `json:"model,omitempty"`      // Model override
+	NeverSend bool   `json:"never_send,omitempty"` // Never send this session's content to the LLM
+}
+
+// LogConfig holds server logging settings
+type LogConfig struct {
+	Level      string            `json:"level,omitempty"`       // debug, info, warn, error (default info)
+	Format     string            `json:"format,omitempty"`      // text or json (default text)
+	File       string            `json:"file,omitempty"`        // Also log to this file; relative to the config dir
+	MaxSizeMB  int               `json:"max_size_mb,omitempty"` // Rotate the file beyond this size (default 10)
+	MaxBackups int               `json:"max_backups,omitempty"` // Rotated files to keep (default 3)
+	Subsystems map[string]string `json:"subsystems,omitempty"`  // Per-subsystem levels, e.g. {"monitor": "debug"}
+}
+
 // Config represents the unified application configuration stored in runtime.json
 // This file serves as both persistent configuration and runtime state
 type Config struct {
+	// Schema version, upgraded on load by the migrations in schema.go
+	Version int `json:"version"`
+
 	// Persistent configuration fields
 	PIN            string `json:"pin,omitempty"`
 	Port           string `json:"port,omitempty"`
@@ -57,6 +157,12 @@ type Config struct {
 	DefaultSession string `json:"default_session,omitempty"`
 	DefaultDir     string `json:"default_dir,omitempty"`
 
+	// Bearer token for /metrics, separate from the PIN (empty = endpoint disabled)
+	MetricsToken string `json:"metrics_token,omitempty"`
+
+	// Logging (level, format, optional rotating file)
+	Log *LogConfig `json:"log,omitempty"`
+
 	// Runtime state field (updated on startup, cleared on exit)
 	PID int `json:"pid"`
 
@@ -69,8 +175,20 @@ type Config struct {
 	// Email notification configuration
 	Email *EmailConfig `json:"email,omitempty"`
 
+	// Notification channels besides email
+	NotifyChannels []NotifyChannelConfig `json:"notify_channels,omitempty"`
+
+	// Browser push (VAPID keys and subscriptions)
+	WebPush *WebPushConfig `json:"web_push,omitempty"`
+
+	// Notification routing rules (empty = notify all channels once per state)
+	NotifyRules []NotifyRule `json:"notify_rules,omitempty"`
+
 	// Per-session notification settings
 	SessionNotify []SessionNotifySettings `json:"session_notify,omitempty"`
+
+	// Per-session AI monitor overrides
+	SessionMonitor []SessionMonitorSettings `json:"session_monitor,omitempty"`
 }
 
 // DefaultConfigDir returns the default config directory
@@ -87,115 +205,107 @@ func ConfigPath() string {
 	return filepath.Join(DefaultConfigDir(), "runtime.json")
 }
 
-// Load loads configuration from runtime.json
+// Load reads runtime.json from disk, bypassing the in-memory store, and upgrades it to the
+// current version. Used by admin commands that inspect a running server's file; the server
+// itself uses Current. Values are not validated
 func Load() (*Config, error) {
-	cfg := &Config{
-		Port:           "8080",
-		Autocreate:     true,
-		DefaultSession: "Main",
-	}
+	return load(true)
+}
+
+// LoadSealed is Load without decrypting secrets, which stay as stored. Commands that only
+// need the PID, port or other plain settings use it, since the key is often only in the
+// server's environment
+func LoadSealed() (*Config, error) {
+	return load(false)
+}
 
+func load(decrypt bool) (*Config, error) {
 	data, err := os.ReadFile(ConfigPath())
 	if err != nil {
 		if os.IsNotExist(err) {
-			return cfg, nil
+			return defaultConfig(), nil
 		}
 		return nil, err
 	}
 
-	if err := json.Unmarshal(data, cfg); err != nil {
-		return nil, err
-	}
-
-	return cfg, nil
+	cfg, _, err := parse(data, decrypt)
+	return cfg, err
 }
 
-// Save saves configuration to runtime.json
+// Save replaces the whole configuration
 func Save(cfg *Config) error {
-	dir := DefaultConfigDir()
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return err
-	}
-
-	data, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	return os.WriteFile(ConfigPath(), data, 0600)
+	return Update(func(c *Config) error {
+		*c = *cfg.clone()
+		return nil
+	})
 }
 
 // UpdatePID updates the PID field in the config and saves to file
 func UpdatePID(pid int) error {
-	cfg, err := Load()
-	if err != nil {
-		return err
-	}
-	cfg.PID = pid
-	return Save(cfg)
+	return Update(func(cfg *Config) error {
+		cfg.PID = pid
+		return nil
+	})
 }
 
 // ClearPID sets PID to 0 (indicating not running) and saves to file
 func ClearPID() error {
-	cfg, err := Load()
-	if err != nil {
-		// If file doesn't exist, nothing to clear
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
+	if _, err := os.Stat(ConfigPath()); os.IsNotExist(err) {
+		return nil // Nothing to clear
 	}
-	cfg.PID = 0
-	return Save(cfg)
+	return UpdatePID(0)
 }
 
 // AddPersistentSession adds a session to the persistent sessions list
 func AddPersistentSession(ps PersistentSession) error {
-	configMu.Lock()
-	defer configMu.Unlock()
+	return Update(func(cfg *Config) error {
+		// Check if already exists, update if so
+		for i, existing := range cfg.PersistentSessions {
+			if existing.ID == ps.ID {
+				cfg.PersistentSessions[i] = ps
+				return nil
+			}
+		}
 
-	cfg, err := Load()
-	if err != nil {
-		return err
-	}
+		// Add new persistent session
+		cfg.PersistentSessions = append(cfg.PersistentSessions, ps)
+		return nil
+	})
+}
 
-	// Check if already exists, update if so
-	for i, existing := range cfg.PersistentSessions {
-		if existing.ID == ps.ID {
-			cfg.PersistentSessions[i] = ps
-			return Save(cfg)
+// RenamePersistentSession changes the title a persistent session is restored with
+func RenamePersistentSession(id, title string) error {
+	return Update(func(cfg *Config) error {
+		for i, ps := range cfg.PersistentSessions {
+			if ps.ID == id {
+				if ps.Title == title {
+					return errNoChange
+				}
+				cfg.PersistentSessions[i].Title = title
+				return nil
+			}
 		}
-	}
-
-	// Add new persistent session
-	cfg.PersistentSessions = append(cfg.PersistentSessions, ps)
-	return Save(cfg)
+		return errNoChange
+	})
 }
 
 // RemovePersistentSession removes a session from the persistent sessions list
 func RemovePersistentSession(id string) error {
-	configMu.Lock()
-	defer configMu.Unlock()
-
-	cfg, err := Load()
-	if err != nil {
-		return err
-	}
-
-	// Find and remove
-	for i, ps := range cfg.PersistentSessions {
-		if ps.ID == id {
-			cfg.PersistentSessions = append(cfg.PersistentSessions[:i], cfg.PersistentSessions[i+1:]...)
-			return Save(cfg)
+	return Update(func(cfg *Config) error {
+		// Find and remove
+		for i, ps := range cfg.PersistentSessions {
+			if ps.ID == id {
+				cfg.PersistentSessions = append(cfg.PersistentSessions[:i], cfg.PersistentSessions[i+1:]...)
+				return nil
+			}
 		}
-	}
-
-	return nil // Not found, nothing to remove
+		return errNoChange // Not found, nothing to remove
+	})
 }
 
 // GetPersistentSession returns a persistent session by ID, or nil if not found
 func GetPersistentSession(id string) *PersistentSession {
-	cfg, err := Load()
+	cfg, err := Current()
 	if err != nil {
 		return nil
 	}
@@ -210,7 +320,7 @@ func GetPersistentSession(id string) *PersistentSession {
 
 // GetAllPersistentSessions returns all persistent sessions
 func GetAllPersistentSessions() []PersistentSession {
-	cfg, err := Load()
+	cfg, err := Current()
 	if err != nil {
 		return nil
 	}
@@ -219,7 +329,7 @@ func GetAllPersistentSessions() []PersistentSession {
 
 // GetAIMonitorConfig returns the AI monitor configuration
 func GetAIMonitorConfig() *AIMonitorConfig {
-	cfg, err := Load()
+	cfg, err := Current()
 	if err != nil {
 		return nil
 	}
@@ -228,20 +338,15 @@ func GetAIMonitorConfig() *AIMonitorConfig {
 
 // SaveAIMonitorConfig saves the AI monitor configuration
 func SaveAIMonitorConfig(aiCfg *AIMonitorConfig) error {
-	configMu.Lock()
-	defer configMu.Unlock()
-
-	cfg, err := Load()
-	if err != nil {
-		return err
-	}
-	cfg.AIMonitor = aiCfg
-	return Save(cfg)
+	return Update(func(cfg *Config) error {
+		cfg.AIMonitor = aiCfg
+		return nil
+	})
 }
 
 // GetEmailConfig returns the email notification configuration
 func GetEmailConfig() *EmailConfig {
-	cfg, err := Load()
+	cfg, err := Current()
 	if err != nil {
 		return nil
 	}
@@ -250,20 +355,119 @@ func GetEmailConfig() *EmailConfig {
 
 // SaveEmailConfig saves the email notification configuration
 func SaveEmailConfig(emailCfg *EmailConfig) error {
-	configMu.Lock()
-	defer configMu.Unlock()
+	return Update(func(cfg *Config) error {
+		cfg.Email = emailCfg
+		return nil
+	})
+}
+
+// GetNotifyChannels returns the configured notification channels
+func GetNotifyChannels() []NotifyChannelConfig {
+	cfg, err := Current()
+	if err != nil {
+		return nil
+	}
+	return cfg.NotifyChannels
+}
+
+// SaveNotifyChannels replaces the notification channel list
+func SaveNotifyChannels(channels []NotifyChannelConfig) error {
+	return Update(func(cfg *Config) error {
+		cfg.NotifyChannels = channels
+		return nil
+	})
+}
 
-	cfg, err := Load()
+// GetWebPushConfig returns the web push configuration, or nil before keys are generated
+func GetWebPushConfig() *WebPushConfig {
+	cfg, err := Current()
 	if err != nil {
-		return err
+		return nil
 	}
-	cfg.Email = emailCfg
-	return Save(cfg)
+	return cfg.WebPush
+}
+
+// EnsureVAPIDKeys returns the web push configuration, generating the VAPID keypair on first use
+func EnsureVAPIDKeys(generate func() (publicKey, privateKey string, err error)) (*WebPushConfig, error) {
+	var out *WebPushConfig
+	err := Update(func(cfg *Config) error {
+		if cfg.WebPush != nil && cfg.WebPush.VAPIDPrivateKey != "" {
+			out = cfg.WebPush
+			return errNoChange
+		}
+
+		pub, priv, err := generate()
+		if err != nil {
+			return err
+		}
+		if cfg.WebPush == nil {
+			cfg.WebPush = &WebPushConfig{}
+		}
+		cfg.WebPush.VAPIDPublicKey = pub
+		cfg.WebPush.VAPIDPrivateKey = priv
+		out = cfg.WebPush
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SavePushSubscription adds or replaces a push subscription (keyed by endpoint)
+func SavePushSubscription(sub PushSubscription) error {
+	return Update(func(cfg *Config) error {
+		if cfg.WebPush == nil {
+			cfg.WebPush = &WebPushConfig{}
+		}
+		for i, s := range cfg.WebPush.Subscriptions {
+			if s.Endpoint == sub.Endpoint {
+				cfg.WebPush.Subscriptions[i] = sub
+				return nil
+			}
+		}
+		cfg.WebPush.Subscriptions = append(cfg.WebPush.Subscriptions, sub)
+		return nil
+	})
+}
+
+// RemovePushSubscription deletes a push subscription by endpoint
+func RemovePushSubscription(endpoint string) error {
+	return Update(func(cfg *Config) error {
+		if cfg.WebPush == nil {
+			return errNoChange
+		}
+		subs := cfg.WebPush.Subscriptions[:0]
+		for _, s := range cfg.WebPush.Subscriptions {
+			if s.Endpoint != endpoint {
+				subs = append(subs, s)
+			}
+		}
+		cfg.WebPush.Subscriptions = subs
+		return nil
+	})
+}
+
+// GetNotifyRules returns the notification routing rules
+func GetNotifyRules() []NotifyRule {
+	cfg, err := Current()
+	if err != nil {
+		return nil
+	}
+	return cfg.NotifyRules
+}
+
+// SaveNotifyRules replaces the notification routing rules
+func SaveNotifyRules(rules []NotifyRule) error {
+	return Update(func(cfg *Config) error {
+		cfg.NotifyRules = rules
+		return nil
+	})
 }
 
 // GetSessionNotifyEnabled returns whether notification is enabled for a session
 func GetSessionNotifyEnabled(sessionID string) bool {
-	cfg, err := Load()
+	cfg, err := current.snapshot()
 	if err != nil {
 		return false
 	}
@@ -277,47 +481,73 @@ func GetSessionNotifyEnabled(sessionID string) bool {
 
 // SetSessionNotifyEnabled sets the notification enabled status for a session
 func SetSessionNotifyEnabled(sessionID string, enabled bool) error {
-	configMu.Lock()
-	defer configMu.Unlock()
-
-	cfg, err := Load()
-	if err != nil {
-		return err
-	}
-
-	// Find and update or add new entry
-	found := false
-	for i, s := range cfg.SessionNotify {
-		if s.SessionID == sessionID {
-			cfg.SessionNotify[i].NotifyEnabled = enabled
-			found = true
-			break
+	return Update(func(cfg *Config) error {
+		// Find and update or add new entry
+		for i, s := range cfg.SessionNotify {
+			if s.SessionID == sessionID {
+				cfg.SessionNotify[i].NotifyEnabled = enabled
+				return nil
+			}
 		}
-	}
-	if !found {
 		cfg.SessionNotify = append(cfg.SessionNotify, SessionNotifySettings{
 			SessionID:     sessionID,
 			NotifyEnabled: enabled,
 		})
-	}
-	return Save(cfg)
+		return nil
+	})
 }
 
 // RemoveSessionNotifySettings removes notification settings for a session
 func RemoveSessionNotifySettings(sessionID string) error {
-	configMu.Lock()
-	defer configMu.Unlock()
+	return Update(func(cfg *Config) error {
+		for i, s := range cfg.SessionNotify {
+			if s.SessionID == sessionID {
+				cfg.SessionNotify = append(cfg.SessionNotify[:i], cfg.SessionNotify[i+1:]...)
+				return nil
+			}
+		}
+		return errNoChange
+	})
+}
 
-	cfg, err := Load()
+// GetSessionMonitorSettings returns the AI monitor overrides for a session, or nil if none are set
+func GetSessionMonitorSettings(sessionID string) *SessionMonitorSettings {
+	cfg, err := current.snapshot()
 	if err != nil {
-		return err
+		return nil
 	}
-
-	for i, s := range cfg.SessionNotify {
+	for _, s := range cfg.SessionMonitor {
 		if s.SessionID == sessionID {
-			cfg.SessionNotify = append(cfg.SessionNotify[:i], cfg.SessionNotify[i+1:]...)
-			return Save(cfg)
+			return &s
 		}
 	}
 	return nil
 }
+
+// SetSessionMonitorSettings saves the AI monitor overrides for a session
+func SetSessionMonitorSettings(settings SessionMonitorSettings) error {
+	return Update(func(cfg *Config) error {
+		// Find and update or add new entry
+		for i, s := range cfg.SessionMonitor {
+			if s.SessionID == settings.SessionID {
+				cfg.SessionMonitor[i] = settings
+				return nil
+			}
+		}
+		cfg.SessionMonitor = append(cfg.SessionMonitor, settings)
+		return nil
+	})
+}
+
+// RemoveSessionMonitorSettings removes AI monitor overrides for a session
+func RemoveSessionMonitorSettings(sessionID string) error {
+	return Update(func(cfg *Config) error {
+		for i, s := range cfg.SessionMonitor {
+			if s.SessionID == sessionID {
+				cfg.SessionMonitor = append(cfg.SessionMonitor[:i], cfg.SessionMonitor[i+1:]...)
+				return nil
+			}
+		}
+		return errNoChange
+	})
+}
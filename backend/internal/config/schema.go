@@ -0,0 +1,163 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CurrentVersion is the runtime.json schema version written by this build
+// Bump it and append a migration when existing files need rewriting
+const CurrentVersion = 2
+
+// migrations[v] upgrades a decoded runtime.json from version v to v+1
+var migrations = []func(doc map[string]any) error{
+	migrateV0,
+	migrateV1,
+}
+
+// migrateV0 upgrades files written before versioning. Hand-edited numeric ports used to
+// fail to parse, and analysis intervals below the minimum were silently raised to it
+func migrateV0(doc map[string]any) error {
+	if port, ok := doc["port"].(json.Number); ok {
+		doc["port"] = port.String()
+	}
+	if email, ok := doc["email"].(map[string]any); ok {
+		if port, ok := email["smtp_port"].(string); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(port))
+			if err != nil {
+				return fmt.Errorf("email.smtp_port %q is not a number", port)
+			}
+			email["smtp_port"] = n
+		}
+	}
+	if ai, ok := doc["ai_monitor"].(map[string]any); ok {
+		interval, _ := ai["interval"].(json.Number)
+		if n, _ := interval.Int64(); n < MinAIInterval {
+			ai["interval"] = MinAIInterval
+		}
+	}
+	return nil
+}
+
+// migrateV1 marks the switch to encrypted secrets (see secrets.go). Plain-text values are
+// sealed on the next save; the version bump stops older builds from using "enc:" values as-is
+func migrateV1(doc map[string]any) error {
+	return nil
+}
+
+// ParseInfo describes what Parse found in a file besides the config itself
+type ParseInfo struct {
+	Version int      // Schema version the file was written with (0 = before versioning)
+	Unknown []string // Fields this build doesn't know; they are dropped when the file is next saved
+}
+
+// Parse decodes runtime.json, upgrading older versions with the migrations and decrypting
+// secrets. It does not validate values; see Validate
+func Parse(data []byte) (*Config, ParseInfo, error) {
+	return parse(data, true)
+}
+
+// parse is Parse, optionally leaving encrypted secrets as stored
+func parse(data []byte, decrypt bool) (*Config, ParseInfo, error) {
+	var info ParseInfo
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // Keep integers exact through the re-encode below
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, info, err
+	}
+	if doc == nil {
+		doc = make(map[string]any)
+	}
+
+	if v, ok := doc["version"]; ok {
+		num, _ := v.(json.Number)
+		n, err := num.Int64()
+		if err != nil || n < 0 {
+			return nil, info, fmt.Errorf("version %v is not a version number", v)
+		}
+		info.Version = int(n)
+	}
+	if info.Version > CurrentVersion {
+		return nil, info, fmt.Errorf("version %d was written by a newer winterm-bridge (this one supports %d)", info.Version, CurrentVersion)
+	}
+	for v := info.Version; v < CurrentVersion; v++ {
+		if err := migrations[v](doc); err != nil {
+			return nil, info, fmt.Errorf("upgrading from version %d: %w", v, err)
+		}
+	}
+	doc["version"] = CurrentVersion
+	info.Unknown = unknownFields(doc, reflect.TypeOf(Config{}), "")
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, info, err
+	}
+	cfg := defaultConfig()
+	if err := json.Unmarshal(migrated, cfg); err != nil {
+		return nil, info, err
+	}
+	if !decrypt {
+		return cfg, info, nil
+	}
+	if err := openSecrets(cfg); err != nil {
+		return nil, info, err
+	}
+	return cfg, info, nil
+}
+
+// unknownFields lists the paths in a decoded JSON value that have no matching field in t
+func unknownFields(v any, t reflect.Type, path string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var out []string
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok || t == reflect.TypeOf(time.Time{}) {
+			return nil
+		}
+		// encoding/json matches keys case-insensitively
+		known := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if name != "" && name != "-" {
+				known[strings.ToLower(name)] = t.Field(i).Type
+			}
+		}
+		for _, k := range sortedKeys(obj) {
+			if ft, ok := known[strings.ToLower(k)]; ok {
+				out = append(out, unknownFields(obj[k], ft, path+k+".")...)
+			} else {
+				out = append(out, path+k)
+			}
+		}
+	case reflect.Map:
+		obj, _ := v.(map[string]any)
+		for _, k := range sortedKeys(obj) {
+			out = append(out, unknownFields(obj[k], t.Elem(), path+k+".")...)
+		}
+	case reflect.Slice:
+		arr, _ := v.([]any)
+		for i, e := range arr {
+			out = append(out, unknownFields(e, t.Elem(), fmt.Sprintf("%s[%d].", strings.TrimSuffix(path, "."), i))...)
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMigrates(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		version int
+		check   func(t *testing.T, cfg *Config)
+	}{
+		{
+			name:    "unversioned",
+			in:      `{"port": 9090, "email": {"smtp_port": " 587 "}, "ai_monitor": {"interval": 1}}`,
+			version: 0,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Port != "9090" {
+					t.Errorf("port = %q, want 9090", cfg.Port)
+				}
+				if cfg.Email == nil || cfg.Email.SMTPPort != 587 {
+					t.Errorf("email = %+v, want smtp_port 587", cfg.Email)
+				}
+				if cfg.AIMonitor == nil || cfg.AIMonitor.Interval != MinAIInterval {
+					t.Errorf("ai_monitor = %+v, want interval %d", cfg.AIMonitor, MinAIInterval)
+				}
+			},
+		},
+		{
+			name:    "version 1",
+			in:      `{"version": 1, "port": "8081", "default_session": "Work"}`,
+			version: 1,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Port != "8081" || cfg.DefaultSession != "Work" {
+					t.Errorf("config = %+v", cfg)
+				}
+			},
+		},
+		{
+			name:    "current",
+			in:      `{"version": 2, "port": "8082"}`,
+			version: CurrentVersion,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Port != "8082" || !cfg.Autocreate {
+					t.Errorf("config = %+v, want port 8082 and defaults kept", cfg)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			cfg, info, err := Parse([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Version != tt.version || cfg.Version != CurrentVersion {
+				t.Fatalf("file version %d, config version %d; want %d and %d", info.Version, cfg.Version, tt.version, CurrentVersion)
+			}
+			tt.check(t, cfg)
+
+			// The upgraded config parses back to itself with nothing left to migrate
+			data, err := json.Marshal(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, info, err := Parse(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Version != CurrentVersion || len(info.Unknown) > 0 {
+				t.Fatalf("reparsed info = %+v", info)
+			}
+			if !reflect.DeepEqual(again, cfg) {
+				t.Fatalf("round trip changed the config:\n%+v\n%+v", cfg, again)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name, in, err string
+	}{
+		{"newer version", `{"version": 3}`, "newer winterm-bridge"},
+		{"much newer version", `{"version": 99, "port": "8080"}`, "newer winterm-bridge"},
+		{"negative version", `{"version": -1}`, "not a version number"},
+		{"fractional version", `{"version": 1.5}`, "not a version number"},
+		{"string version", `{"version": "2"}`, "not a version number"},
+		{"failed migration", `{"email": {"smtp_port": "smtp"}}`, "upgrading from version 0"},
+		{"not json", `{"version": 2`, "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			if _, _, err := Parse([]byte(tt.in)); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Parse(%s) error = %v, want %q", tt.in, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseReportsUnknownFields(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	in := `{"version": 2, "bogus": 1, "email": {"smtp_host": "h", "nope": true}, "notify_rules": [{"name": "r", "extra": 1}]}`
+	_, info, err := Parse([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"bogus", "email.nope", "notify_rules[0].extra"}
+	if !reflect.DeepEqual(info.Unknown, want) {
+		t.Fatalf("unknown fields = %q, want %q", info.Unknown, want)
+	}
+}
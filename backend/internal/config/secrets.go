@@ -0,0 +1,335 @@
+package config
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secrets (PIN, AI API key, SMTP password) are stored as "enc:v1:<key id>:<base64 nonce+ciphertext>",
+// sealed with AES-256-GCM. The key comes from, in order:
+//   - WINTERM_CONFIG_KEY: base64 keys separated by commas, the first encrypts
+//   - WINTERM_CONFIG_KEY_COMMAND: a shell command printing keys the same way (e.g. a password manager)
+//   - secret.key next to runtime.json, one key per line, created with mode 0600 on first use
+//
+// A secret can also be a reference resolved when used instead of a value: "env:NAME" or "file:/path"
+
+// Key sources and the prefix of encrypted values
+const (
+	keyEnv        = "WINTERM_CONFIG_KEY"
+	keyCommandEnv = "WINTERM_CONFIG_KEY_COMMAND"
+	sealedPrefix  = "enc:v1:"
+	keyCommandTTL = 10 * time.Second
+)
+
+// KeyPath returns the path of the generated key file
+func KeyPath() string {
+	return filepath.Join(DefaultConfigDir(), "secret.key")
+}
+
+// keyring caches the encryption keys; keys[0] seals, any of them opens
+type keyring struct {
+	mu     sync.Mutex
+	keys   [][]byte
+	source string    // "env", "command" or "file"
+	stamp  fileStamp // Key file version the keys were read from
+}
+
+var ring = &keyring{}
+
+// ErrKeyUnavailable means a secret was encrypted with a key none of the key sources provide
+var ErrKeyUnavailable = errors.New("config encryption key not available")
+
+// get returns the keys, reloading them when the key file changed or when id isn't among them
+// (it was rotated by another process). With create, a missing key file is generated
+func (r *keyring) get(id string, create bool) ([][]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stale := r.keys == nil || (id != "" && findKey(r.keys, id) == nil)
+	if r.source == "file" {
+		if stamp, _ := statFile(KeyPath()); stamp != r.stamp {
+			stale = true
+		}
+	}
+	if stale || (create && len(r.keys) == 0) {
+		keys, source, err := loadKeys(create)
+		if err != nil {
+			return nil, err
+		}
+		r.keys, r.source = keys, source
+		r.stamp, _ = statFile(KeyPath())
+	}
+	return r.keys, nil
+}
+
+// loadKeys reads the keys from the first configured source
+func loadKeys(create bool) ([][]byte, string, error) {
+	if v := os.Getenv(keyEnv); v != "" {
+		keys, err := parseKeys(v)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", keyEnv, err)
+		}
+		return keys, "env", nil
+	}
+	if command := os.Getenv(keyCommandEnv); command != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), keyCommandTTL)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, "sh", "-c", command).Output()
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", keyCommandEnv, err)
+		}
+		keys, err := parseKeys(string(out))
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", keyCommandEnv, err)
+		}
+		return keys, "command", nil
+	}
+
+	path := KeyPath()
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		if !create {
+			return nil, "file", nil
+		}
+		key := newKey()
+		if err := writeFileAtomic(path, []byte(encodeKey(key)+"\n"), 0600); err != nil {
+			return nil, "", err
+		}
+		logger.Info("Generated config encryption key", "path", path)
+		return [][]byte{key}, "file", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, "", fmt.Errorf("%s is accessible by other users (mode %o); chmod 600 it", path, info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	keys, err := parseKeys(string(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	return keys, "file", nil
+}
+
+// parseKeys decodes base64 keys separated by commas or whitespace
+func parseKeys(s string) ([][]byte, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' || r == ' ' || r == '\t' || r == '\r' })
+	if len(fields) == 0 {
+		return nil, errors.New("no key")
+	}
+	keys := make([][]byte, 0, len(fields))
+	for _, f := range fields {
+		key, err := base64.StdEncoding.DecodeString(f)
+		if err != nil || len(key) != 32 {
+			return nil, errors.New("keys must be 32 bytes, base64 encoded (e.g. openssl rand -base64 32)")
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func newKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func encodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// keyID is a short fingerprint identifying which key sealed a value
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+func findKey(keys [][]byte, id string) []byte {
+	for _, k := range keys {
+		if keyID(k) == id {
+			return k
+		}
+	}
+	return nil
+}
+
+// seal encrypts a secret; field is bound as additional data so values can't be swapped
+func seal(field, plain string) (string, error) {
+	keys, err := ring.get("", true)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(keys[0])
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := gcm.Seal(nonce, nonce, []byte(plain), []byte(field))
+	return sealedPrefix + keyID(keys[0]) + ":" + base64.RawStdEncoding.EncodeToString(out), nil
+}
+
+// open decrypts a value produced by seal
+func open(field, sealed string) (string, error) {
+	id, data, ok := strings.Cut(strings.TrimPrefix(sealed, sealedPrefix), ":")
+	raw, err := base64.RawStdEncoding.DecodeString(data)
+	if !ok || err != nil {
+		return "", errors.New("malformed encrypted value")
+	}
+	keys, err := ring.get(id, false)
+	if err != nil {
+		return "", err
+	}
+	key := findKey(keys, id)
+	if key == nil {
+		return "", fmt.Errorf("%w: encrypted with key %s; set %s to that key or restore %s",
+			ErrKeyUnavailable, id, keyEnv, KeyPath())
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	plain, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("decrypting with key %s: %w", id, err)
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// secretFields returns the values encrypted at rest, keyed by JSON path
+func secretFields(cfg *Config) map[string]*string {
+	fields := map[string]*string{"pin": &cfg.PIN}
+	if cfg.AIMonitor != nil {
+		fields["ai_monitor.api_key"] = &cfg.AIMonitor.APIKey
+	}
+	if cfg.Email != nil {
+		fields["email.password"] = &cfg.Email.Password
+	}
+	return fields
+}
+
+// sealSecrets encrypts the plain-text secrets in cfg; references are kept as written
+func sealSecrets(cfg *Config) error {
+	for field, v := range secretFields(cfg) {
+		if *v == "" || IsSecretRef(*v) {
+			continue
+		}
+		sealed, err := seal(field, *v)
+		if err != nil {
+			return fmt.Errorf("encrypting %s: %w", field, err)
+		}
+		*v = sealed
+	}
+	return nil
+}
+
+// openSecrets decrypts the encrypted secrets in cfg
+func openSecrets(cfg *Config) error {
+	for field, v := range secretFields(cfg) {
+		if !strings.HasPrefix(*v, sealedPrefix) {
+			continue
+		}
+		plain, err := open(field, *v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		*v = plain
+	}
+	return nil
+}
+
+// IsSecretRef reports whether a secret setting is an "env:NAME" or "file:/path" reference
+func IsSecretRef(v string) bool {
+	return strings.HasPrefix(v, "env:") || strings.HasPrefix(v, "file:")
+}
+
+// ResolveSecret returns the value of a secret setting, following "env:NAME" and "file:/path"
+// references; other values are returned unchanged
+func ResolveSecret(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, "env:"):
+		name := strings.TrimPrefix(v, "env:")
+		val, ok := os.LookupEnv(name)
+		if !ok || val == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return val, nil
+	case strings.HasPrefix(v, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(v, "file:"))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return v, nil
+}
+
+// RotateKey re-encrypts the secrets in runtime.json with a new key. With the generated key
+// file, a new key is created and the old one removed once nothing uses it. With
+// WINTERM_CONFIG_KEY or a key command, the caller lists the new key first and the old one
+// after it, and RotateKey re-encrypts everything with the new one
+// It returns the key source so the caller can say what to do next
+func RotateKey() (source string, err error) {
+	keys, err := ring.get("", false)
+	if err != nil {
+		return "", err
+	}
+	source = ring.source
+
+	var fresh []byte
+	if source == "file" {
+		// Keep the old keys until every value is re-encrypted
+		fresh = newKey()
+		lines := []string{encodeKey(fresh)}
+		for _, k := range keys {
+			lines = append(lines, encodeKey(k))
+		}
+		if err := writeFileAtomic(KeyPath(), []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+			return source, err
+		}
+	}
+
+	// Saving seals every secret again with the first key
+	if err := Update(func(cfg *Config) error { return nil }); err != nil {
+		return source, err
+	}
+
+	if fresh != nil {
+		if err := writeFileAtomic(KeyPath(), []byte(encodeKey(fresh)+"\n"), 0600); err != nil {
+			return source, err
+		}
+	}
+	return source, nil
+}
@@ -0,0 +1,327 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"winterm-bridge/internal/logging"
+)
+
+var logger = logging.For("config")
+
+// WatchInterval is how often Watch checks runtime.json for external edits
+const WatchInterval = 2 * time.Second
+
+// Subscriber is called after the config changes, with the previous and new values
+// Both are shared snapshots and must not be modified
+type Subscriber func(old, new *Config)
+
+// store is the process-wide in-memory copy of runtime.json
+// Snapshots are never modified after they are stored: updates build a new one, so
+// readers only need the lock to fetch the pointer
+type store struct {
+	mu     sync.RWMutex
+	cfg    *Config
+	stamp  fileStamp // runtime.json as last read or written by us
+	sum    [32]byte  // sha256 of that content
+	reject fileStamp // external edit that failed to parse or validate
+
+	subMu  sync.Mutex
+	subs   map[int]Subscriber
+	nextID int
+}
+
+// fileStamp identifies a version of a file cheaply
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+var current = &store{subs: make(map[int]Subscriber)}
+
+// errNoChange lets an Update function skip the write when it changed nothing
+var errNoChange = errors.New("no change")
+
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{size: info.Size(), modTime: info.ModTime()}, nil
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Version:        CurrentVersion,
+		Port:           "8080",
+		Autocreate:     true,
+		DefaultSession: "Main",
+	}
+}
+
+// clone returns a deep copy of cfg
+func (cfg *Config) clone() *Config {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		panic(fmt.Sprintf("config: marshal: %v", err))
+	}
+	out := &Config{}
+	if err := json.Unmarshal(data, out); err != nil {
+		panic(fmt.Sprintf("config: unmarshal: %v", err))
+	}
+	return out
+}
+
+// snapshot returns the current config, loading runtime.json on first use
+func (s *store) snapshot() (*Config, error) {
+	s.mu.RLock()
+	cfg := s.cfg
+	s.mu.RUnlock()
+	if cfg != nil {
+		return cfg, nil
+	}
+
+	s.mu.Lock()
+	old, err := s.refreshLocked(false)
+	cfg = s.cfg
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	s.notify(old, cfg)
+	return cfg, nil
+}
+
+// refreshLocked rereads runtime.json if it changed since we last read or wrote it (or always
+// with force). It returns the replaced snapshot when the content changed, else nil
+// An edit that fails to parse or validate is rejected: the in-memory config is kept
+// Caller must hold s.mu
+func (s *store) refreshLocked(force bool) (*Config, error) {
+	path := ConfigPath()
+	stamp, err := statFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if s.cfg == nil {
+				s.cfg = defaultConfig()
+			}
+			s.reject = fileStamp{}
+			return nil, nil
+		}
+		return nil, err
+	}
+	if s.cfg != nil && !force && (stamp == s.stamp || stamp == s.reject) {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if s.cfg != nil && sum == s.sum {
+		s.stamp = stamp
+		return nil, nil
+	}
+
+	cfg, info, err := Parse(data)
+	if err != nil {
+		s.reject = stamp
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := Validate(cfg); err != nil {
+		s.reject = stamp
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if info.Version < CurrentVersion {
+		logger.Info("Upgraded runtime.json, saved on the next change", "from_version", info.Version, "to_version", CurrentVersion)
+	}
+	if len(info.Unknown) > 0 {
+		logger.Warn("Ignoring unknown fields in runtime.json", "fields", strings.Join(info.Unknown, ", "))
+	}
+
+	old := s.cfg
+	s.cfg, s.stamp, s.sum, s.reject = cfg, stamp, sum, fileStamp{}
+	if old == nil {
+		return nil, nil // First load isn't a change
+	}
+	return old, nil
+}
+
+// update applies fn to a copy of the config, writes it atomically and notifies subscribers
+func (s *store) update(fn func(cfg *Config) error) error {
+	s.mu.Lock()
+	// Pick up external edits first so they aren't overwritten
+	old, err := s.refreshLocked(false)
+	if err == nil && s.reject != (fileStamp{}) {
+		err = errors.New("runtime.json has an invalid edit")
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("not saving over the file until it is fixed: %w", err)
+	}
+
+	// Subscribers haven't seen an external edit picked up above; report it with this change
+	prev := s.cfg
+	if old != nil {
+		prev = old
+	}
+	fail := func(err error) error {
+		cfg := s.cfg
+		s.mu.Unlock()
+		s.notify(prev, cfg)
+		return err
+	}
+
+	next := s.cfg.clone()
+	if err := fn(next); errors.Is(err, errNoChange) {
+		return fail(nil)
+	} else if err != nil {
+		return fail(err)
+	}
+	next.Version = CurrentVersion
+	if err := Validate(next); err != nil {
+		return fail(err)
+	}
+	sealed := next.clone()
+	if err := sealSecrets(sealed); err != nil {
+		return fail(err)
+	}
+	data, err := json.MarshalIndent(sealed, "", "  ")
+	if err != nil {
+		return fail(err)
+	}
+	if err := writeFileAtomic(ConfigPath(), data, 0600); err != nil {
+		return fail(err)
+	}
+	stamp, _ := statFile(ConfigPath())
+	s.cfg, s.stamp, s.sum = next, stamp, sha256.Sum256(data)
+	s.mu.Unlock()
+
+	s.notify(prev, next)
+	return nil
+}
+
+// notify calls every subscriber when old and new differ
+// Called without s.mu held, so subscribers may read or update the config
+func (s *store) notify(old, new *Config) {
+	if old == nil || reflect.DeepEqual(old, new) {
+		return
+	}
+	s.subMu.Lock()
+	subs := make([]Subscriber, 0, len(s.subs))
+	for _, fn := range s.subs {
+		subs = append(subs, fn)
+	}
+	s.subMu.Unlock()
+	for _, fn := range subs {
+		fn(old, new)
+	}
+}
+
+// writeFileAtomic writes data to a temp file in the same directory and renames it over path,
+// so readers never see a partial file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Current returns a copy of the in-memory config, loading runtime.json on first use
+func Current() (*Config, error) {
+	cfg, err := current.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.clone(), nil
+}
+
+// Update changes the config under the store lock: fn edits a copy, which is then saved
+// atomically and announced to subscribers. Returning an error from fn aborts the update
+func Update(fn func(cfg *Config) error) error {
+	return current.update(fn)
+}
+
+// Subscribe registers fn to be called after every config change, whether made through
+// Update or by editing runtime.json. It returns a function that unsubscribes
+func Subscribe(fn Subscriber) (unsubscribe func()) {
+	current.subMu.Lock()
+	id := current.nextID
+	current.nextID++
+	current.subs[id] = fn
+	current.subMu.Unlock()
+	return func() {
+		current.subMu.Lock()
+		delete(current.subs, id)
+		current.subMu.Unlock()
+	}
+}
+
+// Reload rereads runtime.json now and notifies subscribers if it changed
+// An invalid file is rejected and the current config kept
+func Reload() error {
+	current.mu.Lock()
+	old, err := current.refreshLocked(true)
+	cfg := current.cfg
+	current.mu.Unlock()
+	current.notify(old, cfg)
+	return err
+}
+
+// Watch polls runtime.json for external edits until ctx ends, applying valid ones
+// Invalid edits are logged once and ignored until the file changes again
+func Watch(ctx context.Context) {
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current.mu.Lock()
+		old, err := current.refreshLocked(false)
+		cfg := current.cfg
+		current.mu.Unlock()
+
+		if err != nil {
+			logger.Error("Ignoring invalid edit to runtime.json", "err", err)
+		} else if old != nil && !reflect.DeepEqual(old, cfg) { // Re-encryption alone changes nothing
+			logger.Info("runtime.json changed, applying")
+			current.notify(old, cfg)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// newTestStore returns an empty store over runtime.json in a fresh HOME, with the key
+// read from secret.key there
+func newTestStore(t *testing.T) *store {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(keyEnv, "")
+	t.Setenv(keyCommandEnv, "")
+	return &store{subs: make(map[int]Subscriber)}
+}
+
+func readConfigFile(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile(ConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func writeConfigFile(t *testing.T, content string) {
+	t.Helper()
+	if err := os.WriteFile(ConfigPath(), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateSavesSealedSecretsThatParseBack(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.update(func(cfg *Config) error {
+		cfg.Port = "9000"
+		cfg.PIN = "834921"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := readConfigFile(t)
+	if strings.Contains(saved, "834921") || !strings.Contains(saved, sealedPrefix) {
+		t.Fatalf("PIN not sealed in %s", saved)
+	}
+	cfg, info, err := Parse([]byte(saved))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != CurrentVersion || cfg.Port != "9000" || cfg.PIN != "834921" {
+		t.Fatalf("parsed version %d, port %q, pin %q", info.Version, cfg.Port, cfg.PIN)
+	}
+}
+
+func TestUpdateUpgradesOldFile(t *testing.T) {
+	s := newTestStore(t)
+	if err := os.MkdirAll(DefaultConfigDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeConfigFile(t, `{"port": 9001, "email": {"smtp_port": "25"}}`)
+
+	if err := s.update(func(cfg *Config) error {
+		cfg.DefaultSession = "Work"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, info, err := Parse([]byte(readConfigFile(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != CurrentVersion || cfg.Port != "9001" || cfg.Email.SMTPPort != 25 || cfg.DefaultSession != "Work" {
+		t.Fatalf("saved version %d, config %+v", info.Version, cfg)
+	}
+}
+
+func TestUpdateRejectsInvalidChange(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.update(func(cfg *Config) error {
+		cfg.Port = "9000"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	before := readConfigFile(t)
+
+	err := s.update(func(cfg *Config) error {
+		cfg.Port = "70000"
+		return nil
+	})
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("update error = %v, want a ValidationError", err)
+	}
+	if readConfigFile(t) != before || s.cfg.Port != "9000" {
+		t.Fatal("rejected change was applied")
+	}
+
+	// Errors from the edit itself abort too; errNoChange skips the write quietly
+	if err := s.update(func(cfg *Config) error {
+		cfg.Port = "9002"
+		return errors.New("stop")
+	}); err == nil || err.Error() != "stop" {
+		t.Fatalf("update error = %v, want stop", err)
+	}
+	if err := s.update(func(cfg *Config) error {
+		cfg.Port = "9003"
+		return errNoChange
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if readConfigFile(t) != before || s.cfg.Port != "9000" {
+		t.Fatal("aborted change was applied")
+	}
+}
+
+func TestUpdateRefusesToOverwriteInvalidEdit(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.update(func(cfg *Config) error {
+		cfg.Port = "9000"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, edit := range []string{
+		`{"version": 2, "port": "not a port"}`,
+		`{"version": 2, "port": `,
+		`{"version": 3}`,
+	} {
+		writeConfigFile(t, edit)
+		for attempt := 0; attempt < 2; attempt++ {
+			called := false
+			err := s.update(func(cfg *Config) error {
+				called = true
+				return nil
+			})
+			if err == nil || !strings.Contains(err.Error(), "not saving over the file") {
+				t.Fatalf("edit %s, attempt %d: update error = %v", edit, attempt, err)
+			}
+			if called {
+				t.Fatalf("edit %s: update applied a change over the invalid file", edit)
+			}
+			if got := readConfigFile(t); got != edit {
+				t.Fatalf("edit %s was overwritten with %s", edit, got)
+			}
+			if s.cfg.Port != "9000" {
+				t.Fatalf("edit %s: in-memory port = %q", edit, s.cfg.Port)
+			}
+		}
+	}
+
+	// Once the edit is fixed it is picked up and kept by the next save
+	writeConfigFile(t, `{"version": 2, "port": "9100"}`)
+	if err := s.update(func(cfg *Config) error {
+		cfg.DefaultSession = "Work"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := Parse([]byte(readConfigFile(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != "9100" || cfg.DefaultSession != "Work" {
+		t.Fatalf("saved port %q, default session %q", cfg.Port, cfg.DefaultSession)
+	}
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"winterm-bridge/internal/logging"
+)
+
+// MinAIInterval is the shortest AI monitor analysis interval in seconds
+const MinAIInterval = 5
+
+// FieldError is a problem with one config value, named by its JSON path (e.g. "email.smtp_port")
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every invalid field found in a config
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// fieldErrors collects FieldErrors while validating
+type fieldErrors struct {
+	list []FieldError
+}
+
+func (f *fieldErrors) add(field, format string, a ...any) {
+	f.list = append(f.list, FieldError{Field: field, Message: fmt.Sprintf(format, a...)})
+}
+
+// merge adds the fields of a section's validation error
+func (f *fieldErrors) merge(prefix string, err error) {
+	if v, ok := err.(*ValidationError); ok {
+		for _, fe := range v.Fields {
+			f.list = append(f.list, FieldError{Field: prefix + fe.Field, Message: fe.Message})
+		}
+	}
+}
+
+func (f *fieldErrors) err() error {
+	if len(f.list) == 0 {
+		return nil
+	}
+	sort.SliceStable(f.list, func(i, j int) bool { return f.list[i].Field < f.list[j].Field })
+	return &ValidationError{Fields: f.list}
+}
+
+// Validate checks every value in cfg and returns a *ValidationError listing the bad ones
+// Zero values mean "use the default" except where a migration fills one in (see migrations)
+func Validate(cfg *Config) error {
+	f := &fieldErrors{}
+	if cfg.Version > CurrentVersion {
+		f.add("version", "%d is newer than this build supports (%d)", cfg.Version, CurrentVersion)
+	}
+	if cfg.Port != "" {
+		if p, err := strconv.Atoi(cfg.Port); err != nil || p < 1 || p > 65535 {
+			f.add("port", "%q is not a port number (1-65535)", cfg.Port)
+		}
+	}
+	if l := cfg.Log; l != nil {
+		if l.Level != "" && !validLogLevel(l.Level) {
+			f.add("log.level", "%q is not debug, info, warn or error", l.Level)
+		}
+		if format := strings.ToLower(l.Format); format != "" && format != "text" && format != "json" {
+			f.add("log.format", "%q is not text or json", l.Format)
+		}
+		for name, level := range l.Subsystems {
+			if !validLogLevel(level) {
+				f.add("log.subsystems."+name, "%q is not debug, info, warn or error", level)
+			}
+		}
+		if l.MaxSizeMB < 0 || l.MaxBackups < 0 {
+			f.add("log", "max_size_mb and max_backups must not be negative")
+		}
+	}
+	if err := validateSecretRef(cfg.PIN); err != nil {
+		f.add("pin", "%v", err)
+	}
+	if cfg.AIMonitor != nil {
+		f.merge("ai_monitor.", cfg.AIMonitor.Validate())
+	}
+	if cfg.Email != nil {
+		f.merge("email.", cfg.Email.Validate())
+	}
+	for i := range cfg.SessionMonitor {
+		f.merge(fmt.Sprintf("session_monitor[%d].", i), cfg.SessionMonitor[i].Validate())
+	}
+	return f.err()
+}
+
+// Validate checks a session's AI monitor overrides; field names are relative to the entry
+func (s *SessionMonitorSettings) Validate() error {
+	f := &fieldErrors{}
+	if s.Lines < 0 {
+		f.add("lines", "must not be negative")
+	}
+	if s.Interval != 0 && s.Interval < MinAIInterval {
+		f.add("interval", "must be at least %d seconds", MinAIInterval)
+	}
+	return f.err()
+}
+
+// Validate checks the AI monitor settings; field names are relative to the section
+func (c *AIMonitorConfig) Validate() error {
+	f := &fieldErrors{}
+	if err := validateSecretRef(c.APIKey); err != nil {
+		f.add("api_key", "%v", err)
+	}
+	if c.Endpoint != "" && !isHTTPURL(c.Endpoint) {
+		f.add("endpoint", "%q is not an http(s) URL", c.Endpoint)
+	}
+	if c.Lines < 0 {
+		f.add("lines", "must not be negative")
+	}
+	if c.Interval < MinAIInterval {
+		f.add("interval", "must be at least %d seconds", MinAIInterval)
+	}
+	if c.HistorySize < 0 {
+		f.add("history_size", "must not be negative")
+	}
+	if c.DailyBudget < 0 {
+		f.add("daily_budget", "must not be negative")
+	}
+	for model, p := range c.Prices {
+		if p.PromptPer1K < 0 || p.CompletionPer1K < 0 {
+			f.add("prices."+model, "prices must not be negative")
+		}
+	}
+	if c.Concurrency < 0 {
+		f.add("concurrency", "must not be negative")
+	}
+	if c.MaxIdleInterval != 0 && c.MaxIdleInterval < MinAIInterval {
+		f.add("max_idle_interval", "must be at least %d seconds", MinAIInterval)
+	}
+	for i, p := range c.RedactPatterns {
+		if _, err := CompileRedactPattern(p); err != nil {
+			f.add(fmt.Sprintf("redact_patterns[%d]", i), "%v", err)
+		}
+	}
+	return f.err()
+}
+
+// Validate checks the email settings; field names are relative to the section
+func (c *EmailConfig) Validate() error {
+	f := &fieldErrors{}
+	if err := validateSecretRef(c.Password); err != nil {
+		f.add("password", "%v", err)
+	}
+	if c.SMTPPort < 0 || c.SMTPPort > 65535 {
+		f.add("smtp_port", "%d is not a port number (1-65535)", c.SMTPPort)
+	}
+	if c.FromAddress != "" {
+		if _, err := mail.ParseAddress(c.FromAddress); err != nil {
+			f.add("from_address", "%q is not an email address", c.FromAddress)
+		}
+	}
+	for _, list := range [][2]string{{"to_address", c.ToAddress}, {"cc_address", c.CCAddress}} {
+		field := list[0]
+		for _, addr := range strings.Split(list[1], ",") {
+			if addr = strings.TrimSpace(addr); addr == "" {
+				continue
+			}
+			if _, err := mail.ParseAddress(addr); err != nil {
+				f.add(field, "%q is not an email address", addr)
+			}
+		}
+	}
+	if c.NotifyDelay < 0 {
+		f.add("notify_delay", "must not be negative")
+	}
+	switch strings.ToLower(c.AuthMethod) {
+	case "", "plain", "login", "cram-md5", "none":
+	default:
+		f.add("auth_method", "%q is not plain, login, cram-md5 or none", c.AuthMethod)
+	}
+	if c.Timeout < 0 {
+		f.add("timeout", "must not be negative")
+	}
+	if c.PublicURL != "" && !isHTTPURL(c.PublicURL) {
+		f.add("public_url", "%q is not an http(s) URL", c.PublicURL)
+	}
+	return f.err()
+}
+
+// validateSecretRef checks the syntax of "env:" and "file:" references
+func validateSecretRef(v string) error {
+	switch {
+	case strings.HasPrefix(v, "env:") && strings.TrimPrefix(v, "env:") == "":
+		return errors.New("env: needs a variable name")
+	case strings.HasPrefix(v, "file:") && !filepath.IsAbs(strings.TrimPrefix(v, "file:")):
+		return errors.New("file: needs an absolute path")
+	}
+	return nil
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func validLogLevel(level string) bool {
+	_, err := logging.ParseLevel(level)
+	return err == nil
+}
+
+// emptyMatchProbes are texts a redact pattern is tried on to find empty matches
+var emptyMatchProbes = []string{"", "a", "a b", "0\n"}
+
+// CompileRedactPattern compiles a user redact pattern
+// Patterns that match empty text (x*, \b, ^) are rejected: they would insert markers
+// between characters instead of hiding anything
+func CompileRedactPattern(p string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, err
+	}
+	for _, probe := range emptyMatchProbes {
+		for _, m := range re.FindAllStringIndex(probe, -1) {
+			if m[0] == m[1] {
+				return nil, fmt.Errorf("%q matches the empty string", p)
+			}
+		}
+	}
+	return re, nil
+}
@@ -0,0 +1,195 @@
+package email
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ansiPattern matches CSI sequences (including SGR) and OSC sequences
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// ansiColors is the xterm default 16-color palette
+var ansiColors = [16]string{
+	"#000000", "#cd3131", "#0dbc79", "#e5e510", "#2472c8", "#bc3fbc", "#11a8cd", "#e5e5e5",
+	"#666666", "#f14c4c", "#23d18b", "#f5f543", "#3b8eea", "#d670d6", "#29b8db", "#ffffff",
+}
+
+// sgrState is the text style accumulated from SGR sequences
+type sgrState struct {
+	fg, bg    string
+	bold      bool
+	italic    bool
+	underline bool
+}
+
+func (s sgrState) style() string {
+	var parts []string
+	if s.fg != "" {
+		parts = append(parts, "color:"+s.fg)
+	}
+	if s.bg != "" {
+		parts = append(parts, "background-color:"+s.bg)
+	}
+	if s.bold {
+		parts = append(parts, "font-weight:bold")
+	}
+	if s.italic {
+		parts = append(parts, "font-style:italic")
+	}
+	if s.underline {
+		parts = append(parts, "text-decoration:underline")
+	}
+	return strings.Join(parts, ";")
+}
+
+// ANSIToHTML converts terminal output to HTML-escaped text with inline-styled spans
+// Only SGR (color/style) sequences are rendered; other escape sequences are dropped
+func ANSIToHTML(s string) string {
+	var b strings.Builder
+	var state sgrState
+	open := false
+
+	closeSpan := func() {
+		if open {
+			b.WriteString("</span>")
+			open = false
+		}
+	}
+
+	last := 0
+	for _, loc := range ansiPattern.FindAllStringIndex(s, -1) {
+		writeText(&b, s[last:loc[0]], state, &open)
+		last = loc[1]
+
+		seq := s[loc[0]:loc[1]]
+		if !strings.HasPrefix(seq, "\x1b[") || !strings.HasSuffix(seq, "m") {
+			continue
+		}
+		closeSpan()
+		state = applySGR(state, seq[2:len(seq)-1])
+	}
+	writeText(&b, s[last:], state, &open)
+	closeSpan()
+	return b.String()
+}
+
+// StripANSI removes all escape sequences from terminal output
+func StripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// writeText writes escaped text, opening a styled span if needed
+func writeText(b *strings.Builder, text string, state sgrState, open *bool) {
+	if text == "" {
+		return
+	}
+	if !*open {
+		if style := state.style(); style != "" {
+			fmt.Fprintf(b, `<span style="%s">`, style)
+			*open = true
+		}
+	}
+	b.WriteString(html.EscapeString(text))
+}
+
+// applySGR updates the style from the parameters of one SGR sequence
+func applySGR(state sgrState, params string) sgrState {
+	if params == "" {
+		return sgrState{}
+	}
+	codes := strings.Split(params, ";")
+	for i := 0; i < len(codes); i++ {
+		n, err := strconv.Atoi(codes[i])
+		if err != nil {
+			continue
+		}
+		switch {
+		case n == 0:
+			state = sgrState{}
+		case n == 1:
+			state.bold = true
+		case n == 3:
+			state.italic = true
+		case n == 4:
+			state.underline = true
+		case n == 22:
+			state.bold = false
+		case n == 23:
+			state.italic = false
+		case n == 24:
+			state.underline = false
+		case n >= 30 && n <= 37:
+			state.fg = ansiColors[n-30]
+		case n >= 90 && n <= 97:
+			state.fg = ansiColors[n-90+8]
+		case n == 39:
+			state.fg = ""
+		case n >= 40 && n <= 47:
+			state.bg = ansiColors[n-40]
+		case n >= 100 && n <= 107:
+			state.bg = ansiColors[n-100+8]
+		case n == 49:
+			state.bg = ""
+		case n == 38 || n == 48:
+			color, used := extendedColor(codes[i+1:])
+			i += used
+			if n == 38 {
+				state.fg = color
+			} else {
+				state.bg = color
+			}
+		}
+	}
+	return state
+}
+
+// extendedColor parses "5;n" (256-color) or "2;r;g;b" (truecolor) parameters
+// Returns the CSS color and how many parameters were consumed
+func extendedColor(codes []string) (string, int) {
+	if len(codes) == 0 {
+		return "", 0
+	}
+	switch codes[0] {
+	case "5":
+		if len(codes) < 2 {
+			return "", len(codes)
+		}
+		n, err := strconv.Atoi(codes[1])
+		if err != nil || n < 0 || n > 255 {
+			return "", 2
+		}
+		return xterm256(n), 2
+	case "2":
+		if len(codes) < 4 {
+			return "", len(codes)
+		}
+		var rgb [3]int
+		for j := 0; j < 3; j++ {
+			v, err := strconv.Atoi(codes[1+j])
+			if err != nil || v < 0 || v > 255 {
+				return "", 4
+			}
+			rgb[j] = v
+		}
+		return fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2]), 4
+	}
+	return "", 1
+}
+
+// xterm256 returns the CSS color of an xterm 256-color palette index
+func xterm256(n int) string {
+	switch {
+	case n < 16:
+		return ansiColors[n]
+	case n < 232:
+		n -= 16
+		steps := [6]int{0, 95, 135, 175, 215, 255}
+		return fmt.Sprintf("#%02x%02x%02x", steps[n/36], steps[(n/6)%6], steps[n%6])
+	default:
+		v := 8 + (n-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", v, v, v)
+	}
+}
@@ -0,0 +1,212 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	"winterm-bridge/internal/actions"
+)
+
+// DefaultExcerptLines is the number of terminal lines included in notification emails
+const DefaultExcerptLines = 20
+
+// Notification is the content of a session notification email
+type Notification struct {
+	SessionID    string
+	SessionTitle string
+	Tag          string
+	Description  string
+	Excerpt      string // Last terminal lines, already redacted; may contain ANSI escapes
+	Actions      []actions.Action
+	Time         time.Time
+}
+
+// message is a composed email ready to hand to the SMTP transport
+type message struct {
+	subject string
+	text    string
+	html    string            // Optional; sent as multipart/alternative when set
+	headers map[string]string // Extra headers such as threading
+}
+
+// tagColors maps status tags to severity colors for the HTML badge
+var tagColors = map[string]string{
+	"错误":  "#d93025",
+	"需输入": "#e37400",
+	"需选择": "#e37400",
+	"完毕":  "#188038",
+}
+
+var notificationTemplate = template.Must(template.New("notification").Parse(`<!DOCTYPE html>
+<html>
+<body style="margin:0;padding:16px;background:#f4f4f5;font-family:-apple-system,'Segoe UI',Roboto,'PingFang SC','Microsoft YaHei',sans-serif;color:#1f2937">
+<div style="max-width:720px;margin:0 auto;background:#ffffff;border-radius:8px;padding:20px">
+  <div style="font-size:18px;font-weight:600;margin-bottom:12px">{{.Title}}
+    <span style="display:inline-block;margin-left:8px;padding:2px 10px;border-radius:12px;font-size:13px;color:#ffffff;background:{{.Color}}">{{.Tag}}</span>
+  </div>
+  <p style="font-size:15px;line-height:1.5;margin:0 0 16px">{{.Description}}</p>
+  {{if .Excerpt}}<pre style="margin:0 0 16px;padding:12px;background:#1e1e1e;color:#d4d4d4;border-radius:6px;font-family:Menlo,Consolas,monospace;font-size:12px;line-height:1.4;white-space:pre-wrap;word-break:break-all">{{.Excerpt}}</pre>{{end}}
+  {{if .Actions}}<p style="margin:0 0 16px"><span style="font-size:13px;color:#6b7280;margin-right:8px">快速回复</span>{{range .Actions}}<a href="{{.URL}}" style="display:inline-block;margin-right:8px;padding:6px 14px;border:1px solid #e37400;color:#e37400;border-radius:6px;text-decoration:none;font-family:Menlo,Consolas,monospace">{{.Label}}</a>{{end}}</p>{{end}}
+  {{if .Link}}<p style="margin:0 0 16px"><a href="{{.Link}}" style="display:inline-block;padding:8px 16px;background:#2563eb;color:#ffffff;border-radius:6px;text-decoration:none">打开会话</a></p>{{end}}
+  <p style="font-size:12px;color:#6b7280;margin:0">会话ID: {{.SessionID}} · {{.Time}}<br>此邮件由 WinTerm-Bridge 自动发送</p>
+</div>
+</body>
+</html>
+`))
+
+// SessionLink returns the web UI URL that opens a session, or "" without a public URL
+func SessionLink(publicURL, sessionID string) string {
+	if publicURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(publicURL, "/") + "/?session=" + url.QueryEscape(sessionID)
+}
+
+// notificationMessage composes the plain-text and HTML notification email
+func notificationMessage(n Notification, publicURL string) (*message, error) {
+	link := SessionLink(publicURL, n.SessionID)
+	when := n.Time
+	if when.IsZero() {
+		when = time.Now()
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "会话状态通知\n\n会话: %s\n状态: %s\n描述: %s\n\n", n.SessionTitle, n.Tag, n.Description)
+	if n.Excerpt != "" {
+		fmt.Fprintf(&text, "终端输出:\n%s\n\n", strings.TrimRight(StripANSI(n.Excerpt), "\n"))
+	}
+	var replies []actions.Action
+	for _, a := range n.Actions {
+		if a.URL != "" {
+			replies = append(replies, a)
+		}
+	}
+	if len(replies) > 0 {
+		text.WriteString("快速回复 (链接一次有效):\n")
+		for _, a := range replies {
+			fmt.Fprintf(&text, "  %s: %s\n", a.Label, a.URL)
+		}
+		text.WriteString("\n")
+	}
+	if link != "" {
+		fmt.Fprintf(&text, "打开会话: %s\n\n", link)
+	}
+	fmt.Fprintf(&text, "会话ID: %s\n\n---\n此邮件由 WinTerm-Bridge 自动发送\n", n.SessionID)
+
+	color, ok := tagColors[n.Tag]
+	if !ok {
+		color = "#6b7280"
+	}
+	var body bytes.Buffer
+	err := notificationTemplate.Execute(&body, map[string]any{
+		"Title":       n.SessionTitle,
+		"Tag":         n.Tag,
+		"Color":       template.CSS(color),
+		"Description": n.Description,
+		"Excerpt":     template.HTML(ANSIToHTML(strings.TrimRight(n.Excerpt, "\n"))),
+		"Actions":     replies,
+		"Link":        link,
+		"SessionID":   n.SessionID,
+		"Time":        when.Format("2006-01-02 15:04:05"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every mail for a session references the same root ID so clients thread them together
+	root := fmt.Sprintf("<session-%s@winterm-bridge>", n.SessionID)
+	return &message{
+		subject: fmt.Sprintf("[WinTerm] %s - %s", n.SessionTitle, n.Tag),
+		text:    text.String(),
+		html:    body.String(),
+		headers: map[string]string{
+			"In-Reply-To":  root,
+			"References":   root,
+			"Thread-Topic": mime.QEncoding.Encode("UTF-8", "[WinTerm] "+n.SessionTitle),
+		},
+	}, nil
+}
+
+// build renders the full RFC 5322 message
+func (m *message) build(from, to, cc string) ([]byte, error) {
+	var buf bytes.Buffer
+	writeHeader := func(k, v string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+	}
+
+	writeHeader("From", from)
+	writeHeader("To", to)
+	if cc != "" {
+		writeHeader("Cc", cc)
+	}
+	writeHeader("Subject", mime.QEncoding.Encode("UTF-8", m.subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("Message-ID", newMessageID())
+	writeHeader("MIME-Version", "1.0")
+	for k, v := range m.headers {
+		writeHeader(k, v)
+	}
+
+	if m.html == "" {
+		writeHeader("Content-Type", "text/plain; charset=UTF-8")
+		writeHeader("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQP(&buf, m.text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	writeHeader("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+
+	// Clients show the last part they support, so the HTML goes last
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", m.text},
+		{"text/html; charset=UTF-8", m.html},
+	}
+	for _, p := range parts {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQP(w, p.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeQP writes s quoted-printable encoded with CRLF line endings
+func writeQP(w io.Writer, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(s, "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// newMessageID returns a unique Message-ID header value
+func newMessageID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("<%s.%d@winterm-bridge>", hex.EncodeToString(b), time.Now().UnixNano())
+}
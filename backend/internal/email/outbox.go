@@ -0,0 +1,331 @@
+package email
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"winterm-bridge/internal/logging"
+)
+
+var logger = logging.For("email")
+
+// Outbox retry policy
+const (
+	outboxFile      = "email_outbox.json"
+	outboxTick      = 15 * time.Second
+	retryBaseDelay  = 30 * time.Second
+	retryMaxDelay   = time.Hour
+	maxAttempts     = 10
+	maxMessageAge   = 24 * time.Hour
+	maxOutboxLength = 500
+)
+
+// OutboxItem is one queued email
+type OutboxItem struct {
+	ID          string    `json:"id"`
+	Subject     string    `json:"subject"`
+	From        string    `json:"from"`
+	Recipients  []string  `json:"recipients"`
+	Data        []byte    `json:"data"`
+	Created     time.Time `json:"created"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// OutboxStatus describes a queued email without its body
+type OutboxStatus struct {
+	ID          string    `json:"id"`
+	Subject     string    `json:"subject"`
+	Recipients  []string  `json:"recipients"`
+	Created     time.Time `json:"created"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// deliverer sends a built message; implemented by Sender
+type deliverer interface {
+	IsEnabled() bool
+	deliver(from string, rcpts []string, msg []byte) error
+}
+
+// Outbox is a persistent queue of emails delivered in the background with retries
+type Outbox struct {
+	path   string
+	sender deliverer
+	items  []*OutboxItem
+	mu     sync.Mutex
+	fileMu sync.Mutex
+	wake   chan struct{}
+	stop   chan struct{} // Closed to end the worker; nil while it isn't running
+	done   chan struct{} // Closed when the worker exits
+	loaded bool          // Whether items reflect the file; false after Stop
+	now    func() time.Time
+}
+
+// NewOutbox creates an outbox persisted in dir and loads any queued emails
+func NewOutbox(dir string, sender deliverer) *Outbox {
+	o := &Outbox{
+		path:   filepath.Join(dir, outboxFile),
+		sender: sender,
+		wake:   make(chan struct{}, 1),
+		now:    time.Now,
+	}
+	o.load()
+	o.loaded = true
+	return o
+}
+
+// Start launches the delivery worker; it does nothing if the worker is running
+// Starting again after Stop rereads the queue, which another process may have changed
+func (o *Outbox) Start() {
+	o.mu.Lock()
+	if o.stop != nil {
+		o.mu.Unlock()
+		return
+	}
+	reload := !o.loaded
+	stop, done := make(chan struct{}), make(chan struct{})
+	o.stop, o.done, o.loaded = stop, done, true
+	o.mu.Unlock()
+
+	if reload {
+		o.load()
+	}
+	go o.run(stop, done)
+}
+
+// Stop ends the delivery worker and waits for it to finish the email it is sending;
+// queued emails stay on disk for the next process
+func (o *Outbox) Stop() {
+	o.mu.Lock()
+	stop, done := o.stop, o.done
+	o.stop, o.loaded = nil, false
+	o.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Wake triggers an immediate delivery pass
+func (o *Outbox) Wake() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue adds an email to the outbox and wakes the worker
+func (o *Outbox) Enqueue(subject, from string, rcpts []string, data []byte) error {
+	now := o.now()
+	item := &OutboxItem{
+		ID:          newItemID(),
+		Subject:     subject,
+		From:        from,
+		Recipients:  rcpts,
+		Data:        data,
+		Created:     now,
+		NextAttempt: now,
+	}
+
+	o.mu.Lock()
+	if len(o.items) >= maxOutboxLength {
+		// Drop the oldest so a long outage can't grow the queue without bound
+		logger.Warn("Outbox full, dropping oldest email", "subject", o.items[0].Subject)
+		o.items = o.items[1:]
+	}
+	o.items = append(o.items, item)
+	o.mu.Unlock()
+
+	o.save()
+	o.Wake()
+	return nil
+}
+
+// Status returns the queued emails without their bodies
+func (o *Outbox) Status() []OutboxStatus {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := make([]OutboxStatus, 0, len(o.items))
+	for _, it := range o.items {
+		out = append(out, OutboxStatus{
+			ID:          it.ID,
+			Subject:     it.Subject,
+			Recipients:  it.Recipients,
+			Created:     it.Created,
+			Attempts:    it.Attempts,
+			NextAttempt: it.NextAttempt,
+			LastError:   it.LastError,
+		})
+	}
+	return out
+}
+
+// Flush attempts delivery of every due email once and returns how many remain queued
+// Call Stop first so the worker can't pick the same email
+func (o *Outbox) Flush() int {
+	o.process(nil)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.items)
+}
+
+func (o *Outbox) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(outboxTick)
+	defer ticker.Stop()
+	for {
+		o.process(stop)
+		select {
+		case <-ticker.C:
+		case <-o.wake:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// process delivers due emails one at a time, rescheduling failures with backoff
+// It returns between emails once stop is closed
+func (o *Outbox) process(stop <-chan struct{}) {
+	if !o.sender.IsEnabled() {
+		return
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		item := o.nextDue()
+		if item == nil {
+			return
+		}
+
+		err := o.sender.deliver(item.From, item.Recipients, item.Data)
+
+		o.mu.Lock()
+		now := o.now()
+		if err == nil {
+			o.removeLocked(item.ID)
+			logger.Info("Notification sent", "to", item.Recipients, "subject", item.Subject)
+		} else {
+			item.Attempts++
+			item.LastError = err.Error()
+			if item.Attempts >= maxAttempts || now.Sub(item.Created) > maxMessageAge {
+				o.removeLocked(item.ID)
+				logger.Error("Giving up on email", "subject", item.Subject, "attempts", item.Attempts, "err", err)
+			} else {
+				item.NextAttempt = now.Add(retryDelay(item.Attempts))
+				logger.Warn("Failed to send email", "subject", item.Subject, "attempt", item.Attempts,
+					"retry_at", item.NextAttempt.Format("15:04:05"), "err", err)
+			}
+		}
+		o.mu.Unlock()
+		o.save()
+
+		if err != nil {
+			// The server is likely down; leave the rest for the next pass
+			return
+		}
+	}
+}
+
+// nextDue returns the oldest email whose retry time has come
+func (o *Outbox) nextDue() *OutboxItem {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := o.now()
+	for _, it := range o.items {
+		if !it.NextAttempt.After(now) {
+			return it
+		}
+	}
+	return nil
+}
+
+// removeLocked deletes an item by ID; caller must hold o.mu
+func (o *Outbox) removeLocked(id string) {
+	for i, it := range o.items {
+		if it.ID == id {
+			o.items = append(o.items[:i], o.items[i+1:]...)
+			return
+		}
+	}
+}
+
+// retryDelay returns the exponential backoff before the given attempt's retry
+func retryDelay(attempts int) time.Duration {
+	d := retryBaseDelay << uint(min(attempts-1, 16))
+	if d > retryMaxDelay || d <= 0 {
+		d = retryMaxDelay
+	}
+	return d
+}
+
+// load reads queued emails from disk
+func (o *Outbox) load() {
+	data, err := os.ReadFile(o.path)
+	if os.IsNotExist(err) {
+		o.mu.Lock()
+		o.items = nil
+		o.mu.Unlock()
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to read outbox", "err", err)
+		return
+	}
+	var items []*OutboxItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		logger.Error("Failed to parse outbox", "err", err)
+		return
+	}
+	o.mu.Lock()
+	o.items = items
+	o.mu.Unlock()
+	if len(items) > 0 {
+		logger.Info("Loaded queued emails from outbox", "count", len(items))
+	}
+}
+
+// save writes the queue to disk atomically
+func (o *Outbox) save() {
+	o.mu.Lock()
+	data, err := json.Marshal(o.items)
+	o.mu.Unlock()
+	if err != nil {
+		logger.Error("Failed to encode outbox", "err", err)
+		return
+	}
+
+	o.fileMu.Lock()
+	defer o.fileMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(o.path), 0700); err != nil {
+		logger.Error("Failed to save outbox", "err", err)
+		return
+	}
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		logger.Error("Failed to save outbox", "err", err)
+		return
+	}
+	if err := os.Rename(tmp, o.path); err != nil {
+		logger.Error("Failed to save outbox", "err", err)
+	}
+}
+
+func newItemID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
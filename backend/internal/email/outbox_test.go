@@ -0,0 +1,94 @@
+package email
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// queueTestEmail enqueues one email on a sender whose SMTP server refuses MAIL FROM,
+// driving the outbox with a fake clock
+func queueTestEmail(t *testing.T) (*fakeSMTP, *Outbox, *time.Time) {
+	f := newFakeSMTP(t)
+	f.mailCode = "451 try again later"
+	s := newTestSender(t, f, testEmailConfig())
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	o := s.Outbox()
+	o.now = func() time.Time { return now }
+	if err := o.Enqueue("subject", "bot@example.com", []string{"a@example.com"}, []byte("body")); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	return f, o, &now
+}
+
+func TestOutboxRetryBackoff(t *testing.T) {
+	f, o, now := queueTestEmail(t)
+
+	o.process(nil)
+	status := o.Status()
+	if len(status) != 1 || status[0].Attempts != 1 {
+		t.Fatalf("after first failure: %+v", status)
+	}
+	if want := now.Add(retryBaseDelay); !status[0].NextAttempt.Equal(want) {
+		t.Fatalf("next attempt %v, want %v", status[0].NextAttempt, want)
+	}
+
+	// Not due yet: no connection
+	*now = now.Add(retryBaseDelay - time.Second)
+	o.process(nil)
+	if got := f.connCount(); got != 1 {
+		t.Fatalf("retried before backoff elapsed: %d connections", got)
+	}
+
+	// Each failure doubles the delay
+	*now = now.Add(time.Second)
+	o.process(nil)
+	status = o.Status()
+	if f.connCount() != 2 || status[0].Attempts != 2 {
+		t.Fatalf("second attempt: %d connections, %+v", f.connCount(), status)
+	}
+	if want := now.Add(2 * retryBaseDelay); !status[0].NextAttempt.Equal(want) {
+		t.Fatalf("next attempt %v, want %v", status[0].NextAttempt, want)
+	}
+	if status[0].LastError == "" {
+		t.Error("LastError not recorded")
+	}
+
+	// The queue survives a restart
+	reloaded := NewOutbox(filepath.Dir(o.path), o.sender)
+	if got := reloaded.Status(); len(got) != 1 || got[0].Attempts != 2 {
+		t.Fatalf("reloaded outbox: %+v", got)
+	}
+}
+
+func TestOutboxGivesUpAfterMaxAttempts(t *testing.T) {
+	f, o, now := queueTestEmail(t)
+
+	for i := 1; i <= maxAttempts; i++ {
+		o.process(nil)
+		if got := f.connCount(); got != i {
+			t.Fatalf("attempt %d: %d connections", i, got)
+		}
+		*now = now.Add(retryMaxDelay)
+	}
+	if got := o.Status(); len(got) != 0 {
+		t.Fatalf("still queued after %d attempts: %+v", maxAttempts, got)
+	}
+
+	o.process(nil)
+	if got := f.connCount(); got != maxAttempts {
+		t.Fatalf("retried after giving up: %d connections", got)
+	}
+}
+
+func TestRetryDelayIsCapped(t *testing.T) {
+	if got := retryDelay(1); got != retryBaseDelay {
+		t.Errorf("retryDelay(1) = %v, want %v", got, retryBaseDelay)
+	}
+	for _, attempts := range []int{8, 20, 100} {
+		if got := retryDelay(attempts); got != retryMaxDelay {
+			t.Errorf("retryDelay(%d) = %v, want %v", attempts, got, retryMaxDelay)
+		}
+	}
+}
@@ -3,39 +3,68 @@ package email
 import (
 	"crypto/tls"
 	"fmt"
-	"log"
 	"net"
 	"net/smtp"
 	"strings"
+	"sync"
+	"time"
 
 	"winterm-bridge/internal/config"
 )
 
+// Supported SMTP authentication methods
+const (
+	AuthPlain   = "plain"
+	AuthLogin   = "login"
+	AuthCRAMMD5 = "cram-md5"
+	AuthNone    = "none"
+)
+
+// defaultTimeout bounds one SMTP conversation, from dial to QUIT
+const defaultTimeout = 30 * time.Second
+
 // Sender handles email notifications
+// Notifications are queued in a persistent outbox and delivered in the background
 type Sender struct {
 	config *config.EmailConfig
+	mu     sync.RWMutex
+
+	outbox *Outbox
+	// dial opens the TCP connection; replaceable to talk to an in-process server
+	dial func(network, addr string, timeout time.Duration) (net.Conn, error)
 }
 
-// NewSender creates a new email sender
+// NewSender creates a new email sender and starts its outbox worker
 func NewSender() *Sender {
-	return &Sender{}
+	s := &Sender{dial: net.DialTimeout}
+	s.outbox = NewOutbox(config.DefaultConfigDir(), s)
+	s.outbox.Start()
+	return s
 }
 
 // UpdateConfig updates the email configuration
 func (s *Sender) UpdateConfig(cfg *config.EmailConfig) {
+	s.mu.Lock()
 	s.config = cfg
+	s.mu.Unlock()
+	s.outbox.Wake()
 }
 
-// GetConfig returns the current email configuration
+// GetConfig returns a copy of the current email configuration
 func (s *Sender) GetConfig() *config.EmailConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if s.config == nil {
 		return &config.EmailConfig{}
 	}
-	return s.config
+	cfg := *s.config
+	return &cfg
 }
 
 // IsEnabled returns whether email sending is enabled and properly configured
 func (s *Sender) IsEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if s.config == nil {
 		return false
 	}
@@ -44,85 +73,110 @@ func (s *Sender) IsEnabled() bool {
 		s.config.ToAddress != ""
 }
 
-// SendNotification sends a notification email for a session state change
-func (s *Sender) SendNotification(sessionTitle, sessionID, tag, description string) error {
+// Outbox returns the sender's delivery queue
+func (s *Sender) Outbox() *Outbox {
+	return s.outbox
+}
+
+// SendNotification queues a notification email for a session state change
+// The email has a plain-text and an HTML part and is threaded per session
+func (s *Sender) SendNotification(n Notification) error {
 	if !s.IsEnabled() {
 		return fmt.Errorf("email not configured")
 	}
 
-	subject := fmt.Sprintf("[WinTerm] %s - %s", sessionTitle, tag)
-	body := fmt.Sprintf(`会话状态通知
-
-会话: %s
-状态: %s
-描述: %s
-
-会话ID: %s
-
----
-此邮件由 WinTerm-Bridge 自动发送
-`, sessionTitle, tag, description, sessionID)
+	cfg := s.GetConfig()
+	msg, err := notificationMessage(n, cfg.PublicURL)
+	if err != nil {
+		return fmt.Errorf("failed to render email: %w", err)
+	}
 
-	return s.send(subject, body)
+	from, to, cc := addresses(cfg)
+	data, err := msg.build(from, to, cc)
+	if err != nil {
+		return fmt.Errorf("failed to build message: %w", err)
+	}
+	return s.outbox.Enqueue(msg.subject, from, recipients(to, cc), data)
 }
 
-// send sends an email with the given subject and body
-func (s *Sender) send(subject, body string) error {
-	if s.config == nil {
-		return fmt.Errorf("email not configured")
+// ExcerptLines returns how many terminal lines to include in notification emails
+func (s *Sender) ExcerptLines() int {
+	cfg := s.GetConfig()
+	if cfg.ExcerptLines == 0 {
+		return DefaultExcerptLines
 	}
-
-	from := s.config.FromAddress
-	if from == "" {
-		from = s.config.Username
+	if cfg.ExcerptLines < 0 {
+		return 0
 	}
+	return cfg.ExcerptLines
+}
 
-	to := s.config.ToAddress
-	host := s.config.SMTPHost
-	port := s.config.SMTPPort
-	if port == 0 {
-		port = 587
+// Test tests the email configuration by sending a test email immediately, bypassing the outbox
+func (s *Sender) Test() error {
+	s.mu.RLock()
+	configured := s.config != nil
+	s.mu.RUnlock()
+	if !configured {
+		return fmt.Errorf("email not configured")
 	}
 
-	// Construct email message
-	msg := fmt.Sprintf("From: %s\r\n"+
-		"To: %s\r\n"+
-		"Subject: %s\r\n"+
-		"Content-Type: text/plain; charset=UTF-8\r\n"+
-		"\r\n"+
-		"%s", from, to, subject, body)
-
-	addr := fmt.Sprintf("%s:%d", host, port)
-
-	// Use SSL for port 465, STARTTLS for others
-	var err error
-	if port == 465 {
-		err = s.sendWithSSL(addr, host, from, to, msg)
-	} else {
-		err = s.sendWithSTARTTLS(addr, host, from, to, msg)
+	cfg := s.GetConfig()
+	msg := &message{
+		subject: "WinTerm 邮件测试",
+		text:    "这是一封测试邮件，如果您收到此邮件，说明邮件配置正确。",
 	}
-
+	from, to, cc := addresses(cfg)
+	data, err := msg.build(from, to, cc)
 	if err != nil {
-		log.Printf("[Email] Failed to send: %v", err)
-		return err
+		return fmt.Errorf("failed to build message: %w", err)
 	}
 
-	log.Printf("[Email] Notification sent to %s: %s", to, subject)
+	if err := s.deliver(from, recipients(to, cc), data); err != nil {
+		logger.Warn("Failed to send test email", "err", err)
+		return err
+	}
+	logger.Info("Test email sent", "to", to)
 	return nil
 }
 
-// sendWithSSL sends email using direct SSL connection (port 465)
-func (s *Sender) sendWithSSL(addr, host, from, to, msg string) error {
-	// Create TLS connection
-	tlsConfig := &tls.Config{
-		ServerName: host,
+// deliver sends a built message over SMTP using the current configuration
+func (s *Sender) deliver(from string, rcpts []string, msg []byte) error {
+	cfg := s.GetConfig()
+	if cfg.SMTPHost == "" {
+		return fmt.Errorf("email not configured")
+	}
+	if len(rcpts) == 0 {
+		return fmt.Errorf("no recipients")
+	}
+	password, err := config.ResolveSecret(cfg.Password)
+	if err != nil {
+		return fmt.Errorf("password: %w", err)
+	}
+	cfg.Password = password // cfg is a copy
+
+	host := cfg.SMTPHost
+	port := cfg.SMTPPort
+	if port == 0 {
+		port = 587
+	}
+	timeout := defaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
 	}
+	addr := net.JoinHostPort(host, fmt.Sprint(port))
 
-	conn, err := tls.Dial("tcp", addr, tlsConfig)
+	conn, err := s.dial("tcp", addr, timeout)
 	if err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
 	defer conn.Close()
+	// One deadline for the whole conversation so a stalled server can't block the queue
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	// Use SSL for port 465, STARTTLS for others
+	if port == 465 {
+		conn = tls.Client(conn, &tls.Config{ServerName: host})
+	}
 
 	client, err := smtp.NewClient(conn, host)
 	if err != nil {
@@ -130,23 +184,32 @@ func (s *Sender) sendWithSSL(addr, host, from, to, msg string) error {
 	}
 	defer client.Close()
 
+	if port != 465 {
+		// Try STARTTLS if available
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				return fmt.Errorf("STARTTLS failed: %w", err)
+			}
+		}
+	}
+
 	// Authenticate
-	if s.config.Username != "" && s.config.Password != "" {
-		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, host)
+	if auth := smtpAuth(cfg); auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("server does not support AUTH; set auth_method to none for unauthenticated relays")
+		}
 		if err := client.Auth(auth); err != nil {
 			return fmt.Errorf("authentication failed: %w", err)
 		}
 	}
 
-	// Set sender and recipient
+	// Set sender and recipients
 	if err := client.Mail(from); err != nil {
 		return fmt.Errorf("MAIL FROM failed: %w", err)
 	}
-
-	for _, rcpt := range strings.Split(to, ",") {
-		rcpt = strings.TrimSpace(rcpt)
+	for _, rcpt := range rcpts {
 		if err := client.Rcpt(rcpt); err != nil {
-			return fmt.Errorf("RCPT TO failed: %w", err)
+			return fmt.Errorf("RCPT TO %s failed: %w", rcpt, err)
 		}
 	}
 
@@ -155,87 +218,81 @@ func (s *Sender) sendWithSSL(addr, host, from, to, msg string) error {
 	if err != nil {
 		return fmt.Errorf("DATA failed: %w", err)
 	}
-
-	_, err = w.Write([]byte(msg))
-	if err != nil {
+	if _, err := w.Write(msg); err != nil {
 		return fmt.Errorf("failed to write message: %w", err)
 	}
-
-	err = w.Close()
-	if err != nil {
+	if err := w.Close(); err != nil {
 		return fmt.Errorf("failed to close writer: %w", err)
 	}
 
 	return client.Quit()
 }
 
-// sendWithSTARTTLS sends email using STARTTLS (port 25, 587)
-func (s *Sender) sendWithSTARTTLS(addr, host, from, to, msg string) error {
-	// Connect
-	conn, err := net.Dial("tcp", addr)
-	if err != nil {
-		return fmt.Errorf("failed to connect: %w", err)
-	}
-	defer conn.Close()
-
-	client, err := smtp.NewClient(conn, host)
-	if err != nil {
-		return fmt.Errorf("failed to create SMTP client: %w", err)
+// smtpAuth returns the configured authentication mechanism, or nil for none
+func smtpAuth(cfg *config.EmailConfig) smtp.Auth {
+	method := strings.ToLower(cfg.AuthMethod)
+	if method == AuthNone || (method == "" && cfg.Username == "") {
+		return nil
 	}
-	defer client.Close()
-
-	// Try STARTTLS if available
-	if ok, _ := client.Extension("STARTTLS"); ok {
-		tlsConfig := &tls.Config{ServerName: host}
-		if err := client.StartTLS(tlsConfig); err != nil {
-			return fmt.Errorf("STARTTLS failed: %w", err)
-		}
+	switch method {
+	case AuthLogin:
+		return &loginAuth{username: cfg.Username, password: cfg.Password}
+	case AuthCRAMMD5:
+		return smtp.CRAMMD5Auth(cfg.Username, cfg.Password)
+	default:
+		return smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.SMTPHost)
 	}
+}
 
-	// Authenticate
-	if s.config.Username != "" && s.config.Password != "" {
-		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, host)
-		if err := client.Auth(auth); err != nil {
-			return fmt.Errorf("authentication failed: %w", err)
-		}
-	}
+// loginAuth implements the AUTH LOGIN mechanism, which net/smtp lacks
+type loginAuth struct {
+	username, password string
+}
 
-	// Set sender and recipient
-	if err := client.Mail(from); err != nil {
-		return fmt.Errorf("MAIL FROM failed: %w", err)
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// Like PlainAuth, refuse to send credentials in the clear except to localhost
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, fmt.Errorf("unencrypted connection")
 	}
+	return "LOGIN", nil, nil
+}
 
-	for _, rcpt := range strings.Split(to, ",") {
-		rcpt = strings.TrimSpace(rcpt)
-		if err := client.Rcpt(rcpt); err != nil {
-			return fmt.Errorf("RCPT TO failed: %w", err)
-		}
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
 	}
-
-	// Send message body
-	w, err := client.Data()
-	if err != nil {
-		return fmt.Errorf("DATA failed: %w", err)
+	prompt := strings.ToLower(strings.TrimSpace(string(fromServer)))
+	switch {
+	case strings.HasPrefix(prompt, "username"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(prompt, "password"):
+		return []byte(a.password), nil
 	}
+	return nil, fmt.Errorf("unexpected LOGIN prompt %q", fromServer)
+}
 
-	_, err = w.Write([]byte(msg))
-	if err != nil {
-		return fmt.Errorf("failed to write message: %w", err)
-	}
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
 
-	err = w.Close()
-	if err != nil {
-		return fmt.Errorf("failed to close writer: %w", err)
+// addresses returns the From, To and CC header values
+func addresses(cfg *config.EmailConfig) (from, to, cc string) {
+	from = cfg.FromAddress
+	if from == "" {
+		from = cfg.Username
 	}
-
-	return client.Quit()
+	return from, cfg.ToAddress, cfg.CCAddress
 }
 
-// Test tests the email configuration by sending a test email
-func (s *Sender) Test() error {
-	if s.config == nil {
-		return fmt.Errorf("email not configured")
+// recipients splits comma-separated To and CC lists into envelope recipients
+func recipients(lists ...string) []string {
+	var out []string
+	for _, list := range lists {
+		for _, addr := range strings.Split(list, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				out = append(out, addr)
+			}
+		}
 	}
-
-	return s.send("WinTerm 邮件测试", "这是一封测试邮件，如果您收到此邮件，说明邮件配置正确。")
+	return out
 }
@@ -0,0 +1,244 @@
+package email
+
+import (
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"winterm-bridge/internal/config"
+)
+
+// fakeSMTP is an in-process SMTP server that records what clients send
+type fakeSMTP struct {
+	ln       net.Listener
+	startTLS bool   // Advertise STARTTLS, then refuse it
+	authCode string // Reply to AUTH, default "235 accepted"
+	mailCode string // Reply to MAIL FROM, default "250 OK"
+
+	mu    sync.Mutex
+	conns int
+	auths []string // "mechanism user password"
+	rcpts []string
+	data  []string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeSMTP{ln: ln, authCode: "235 accepted", mailCode: "250 OK"}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	f.mu.Lock()
+	f.conns++
+	f.mu.Unlock()
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-fake")
+			if f.startTLS {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN LOGIN")
+		case "STARTTLS":
+			tp.PrintfLine("454 TLS not available")
+		case "AUTH":
+			f.auth(tp, arg)
+		case "MAIL":
+			tp.PrintfLine("%s", f.mailCode)
+		case "RCPT":
+			f.mu.Lock()
+			f.rcpts = append(f.rcpts, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			f.mu.Unlock()
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.data = append(f.data, string(data))
+			f.mu.Unlock()
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// auth handles AUTH PLAIN with an initial response and AUTH LOGIN's prompts
+func (f *fakeSMTP) auth(tp *textproto.Conn, arg string) {
+	mech, initial, _ := strings.Cut(arg, " ")
+	var user, pass string
+	switch strings.ToUpper(mech) {
+	case "PLAIN":
+		raw, _ := base64.StdEncoding.DecodeString(initial)
+		parts := strings.Split(string(raw), "\x00")
+		if len(parts) == 3 {
+			user, pass = parts[1], parts[2]
+		}
+	case "LOGIN":
+		for _, prompt := range []string{"Username:", "Password:"} {
+			tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(prompt)))
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			answer, _ := base64.StdEncoding.DecodeString(line)
+			if prompt == "Username:" {
+				user = string(answer)
+			} else {
+				pass = string(answer)
+			}
+		}
+	default:
+		tp.PrintfLine("504 unsupported mechanism")
+		return
+	}
+	f.mu.Lock()
+	f.auths = append(f.auths, strings.ToUpper(mech)+" "+user+" "+pass)
+	f.mu.Unlock()
+	tp.PrintfLine("%s", f.authCode)
+}
+
+// recorded returns what clients have sent so far
+func (f *fakeSMTP) recorded() (auths, rcpts, data []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.auths), slices.Clone(f.rcpts), slices.Clone(f.data)
+}
+
+func (f *fakeSMTP) connCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.conns
+}
+
+// newTestSender returns a sender whose connections go to f
+func newTestSender(t *testing.T, f *fakeSMTP, cfg *config.EmailConfig) *Sender {
+	s := &Sender{
+		config: cfg,
+		dial: func(network, addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout(network, f.ln.Addr().String(), timeout)
+		},
+	}
+	s.outbox = NewOutbox(t.TempDir(), s)
+	return s
+}
+
+func testEmailConfig() *config.EmailConfig {
+	return &config.EmailConfig{
+		Enabled:     true,
+		SMTPHost:    "localhost",
+		SMTPPort:    2525,
+		Username:    "bot@example.com",
+		Password:    "hunter2",
+		FromAddress: "bot@example.com",
+		ToAddress:   "a@example.com, b@example.com",
+		CCAddress:   "c@example.com",
+		Timeout:     5,
+	}
+}
+
+func TestDeliverLoginAuthToAllRecipients(t *testing.T) {
+	f := newFakeSMTP(t)
+	cfg := testEmailConfig()
+	cfg.AuthMethod = AuthLogin
+	s := newTestSender(t, f, cfg)
+
+	if err := s.Test(); err != nil {
+		t.Fatalf("Test: %v", err)
+	}
+	auths, rcpts, data := f.recorded()
+	if want := []string{"LOGIN bot@example.com hunter2"}; !slices.Equal(auths, want) {
+		t.Errorf("auths = %q, want %q", auths, want)
+	}
+	if want := []string{"a@example.com", "b@example.com", "c@example.com"}; !slices.Equal(rcpts, want) {
+		t.Errorf("recipients = %q, want %q", rcpts, want)
+	}
+	if len(data) != 1 || !strings.Contains(data[0], "Cc: c@example.com") {
+		t.Errorf("message missing Cc header: %q", data)
+	}
+}
+
+func TestDeliverWithoutAuth(t *testing.T) {
+	f := newFakeSMTP(t)
+	cfg := testEmailConfig()
+	cfg.AuthMethod = AuthNone
+	s := newTestSender(t, f, cfg)
+
+	if err := s.Test(); err != nil {
+		t.Fatalf("Test: %v", err)
+	}
+	auths, _, data := f.recorded()
+	if len(auths) != 0 {
+		t.Errorf("authenticated with auth_method none: %q", auths)
+	}
+	if len(data) != 1 {
+		t.Errorf("delivered %d messages, want 1", len(data))
+	}
+}
+
+func TestDeliverSTARTTLSFailure(t *testing.T) {
+	f := newFakeSMTP(t)
+	f.startTLS = true
+	s := newTestSender(t, f, testEmailConfig())
+
+	err := s.Test()
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS failed") {
+		t.Fatalf("err = %v, want STARTTLS failure", err)
+	}
+	auths, _, data := f.recorded()
+	if len(auths) != 0 || len(data) != 0 {
+		t.Errorf("continued after STARTTLS failed: auths %q, data %d", auths, len(data))
+	}
+}
+
+func TestDeliverAuthFailure(t *testing.T) {
+	f := newFakeSMTP(t)
+	f.authCode = "535 bad credentials"
+	s := newTestSender(t, f, testEmailConfig())
+
+	err := s.Test()
+	if err == nil || !strings.Contains(err.Error(), "authentication failed") {
+		t.Fatalf("err = %v, want authentication failure", err)
+	}
+	auths, _, data := f.recorded()
+	if want := []string{"PLAIN bot@example.com hunter2"}; !slices.Equal(auths, want) {
+		t.Errorf("auths = %q, want %q", auths, want)
+	}
+	if len(data) != 0 {
+		t.Errorf("delivered %d messages after failed auth", len(data))
+	}
+}
@@ -0,0 +1,129 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types pushed to session-list subscribers
+const (
+	TypeAISummary      = "ai_summary"
+	TypeSessionCreated = "session_created"
+	TypeSessionDeleted = "session_deleted"
+	TypeSessionGhosted = "session_ghosted"
+	TypeSessionRevived = "session_revived"
+	TypeSessionRenamed = "session_renamed"
+	TypeClientAttached = "client_attached"
+	TypeClientDetached = "client_detached"
+
+	TypeServerRestarting = "server_restarting"
+)
+
+// subscriberBuffer is the per-subscriber channel size; events are dropped when full
+const subscriberBuffer = 64
+
+// Event is a server-wide notification delivered to event-stream subscribers
+type Event struct {
+	Type      string `json:"type"`
+	SessionID string `json:"session_id,omitempty"`
+	Data      any    `json:"data,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// SessionData is the payload of session lifecycle events
+type SessionData struct {
+	Title    string `json:"title,omitempty"`
+	TmuxName string `json:"tmux_name,omitempty"`
+}
+
+// ClientData is the payload of client attach/detach events
+type ClientData struct {
+	Clients int `json:"clients"` // Number of clients attached after the change
+}
+
+// Hub fans out events to all subscribers
+// A nil *Hub is valid and drops every event, so publishers don't need nil checks
+type Hub struct {
+	subscribers map[chan Event]struct{}
+	closed      bool
+	mu          sync.RWMutex
+}
+
+// NewHub creates a new event hub
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[chan Event]struct{})}
+}
+
+// Subscribe registers a new subscriber and returns its event channel
+func (h *Hub) Subscribe() chan Event {
+	ch := make(chan Event, subscriberBuffer)
+	h.mu.Lock()
+	if h.closed {
+		close(ch)
+	} else {
+		h.subscribers[ch] = struct{}{}
+	}
+	h.mu.Unlock()
+	return ch
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (h *Hub) Unsubscribe(ch chan Event) {
+	h.mu.Lock()
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+	h.mu.Unlock()
+}
+
+// Publish sends an event to all subscribers without blocking
+func (h *Hub) Publish(eventType, sessionID string, data any) {
+	if h == nil {
+		return
+	}
+
+	ev := Event{
+		Type:      eventType,
+		SessionID: sessionID,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// Drop if subscriber is too slow
+		}
+	}
+}
+
+// Close sends a server_restarting event to every subscriber and closes their channels,
+// ending their streams. Later subscribers get an already-closed channel
+func (h *Hub) Close() {
+	if h == nil {
+		return
+	}
+	h.Publish(TypeServerRestarting, "", nil)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// SubscriberCount returns the number of active subscribers
+func (h *Hub) SubscriberCount() int {
+	if h == nil {
+		return 0
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers)
+}
@@ -0,0 +1,119 @@
+// Package health serves liveness and readiness probes for systemd and container healthchecks
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"winterm-bridge/internal/logging"
+)
+
+var logger = logging.For("health")
+
+// checkTimeout bounds each readiness check
+const checkTimeout = 5 * time.Second
+
+// CheckFunc reports a dependency's state; the detail string is shown on success
+type CheckFunc func(ctx context.Context) (detail string, err error)
+
+// Result is the outcome of one check
+type Result struct {
+	Status   string `json:"status"` // "ok" or "fail"
+	Detail   string `json:"detail,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration_ms"`
+}
+
+// Report is the /readyz response body
+type Report struct {
+	Status string            `json:"status"` // "ok" if every check passed
+	Checks map[string]Result `json:"checks"`
+}
+
+// Checker holds the named readiness checks
+type Checker struct {
+	version string
+	started time.Time
+	checks  map[string]CheckFunc
+	mu      sync.RWMutex
+}
+
+// NewChecker creates a checker with no checks; version is reported by /healthz
+func NewChecker(version string) *Checker {
+	return &Checker{version: version, started: time.Now(), checks: make(map[string]CheckFunc)}
+}
+
+// Add registers a readiness check
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.mu.Lock()
+	c.checks[name] = fn
+	c.mu.Unlock()
+}
+
+// Run executes all checks in parallel
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := make(map[string]CheckFunc, len(c.checks))
+	for name, fn := range c.checks {
+		checks[name] = fn
+	}
+	c.mu.RUnlock()
+
+	report := Report{Status: "ok", Checks: make(map[string]Result, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, fn := range checks {
+		wg.Add(1)
+		go func(name string, fn CheckFunc) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			detail, err := fn(ctx)
+			res := Result{Status: "ok", Detail: detail, Duration: time.Since(start).Milliseconds()}
+			if err != nil {
+				res.Status = "fail"
+				res.Error = err.Error()
+			}
+
+			mu.Lock()
+			report.Checks[name] = res
+			if err != nil {
+				report.Status = "fail"
+			}
+			mu.Unlock()
+		}(name, fn)
+	}
+	wg.Wait()
+	return report
+}
+
+// HandleHealthz handles GET /healthz - The process is up and serving HTTP
+func (c *Checker) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":   "ok",
+		"version":  c.version,
+		"uptime_s": int64(time.Since(c.started).Seconds()),
+	})
+}
+
+// HandleReadyz handles GET /readyz - Runs every check; 503 if any fails
+func (c *Checker) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
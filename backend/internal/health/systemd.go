@@ -0,0 +1,61 @@
+package health
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notify sends a state string (e.g. "READY=1") to systemd's notify socket
+// It is a no-op when the service isn't run with Type=notify
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:] // Abstract namespace
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// Watchdog pings systemd's watchdog while readiness checks pass, until ctx ends
+// It does nothing unless WatchdogSec= is set for the unit. A failing check withholds the
+// ping, so systemd restarts the service once the watchdog interval elapses
+func (c *Checker) Watchdog(ctx context.Context) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(usec) * time.Microsecond / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report := c.Run(ctx)
+			if report.Status == "ok" {
+				Notify("WATCHDOG=1")
+				continue
+			}
+			for name, res := range report.Checks {
+				if res.Status != "ok" {
+					logger.Warn("Readiness check failed, withholding watchdog ping", "check", name, "err", res.Error)
+				}
+			}
+		}
+	}
+}
@@ -48,6 +48,7 @@ type chatResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage *Usage `json:"usage,omitempty"`
 	Error *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
@@ -116,6 +117,11 @@ func (p *OpenAICompatProvider) Summarize(ctx context.Context, content string) (*
 		return nil, fmt.Errorf("no response from model")
 	}
 
+	var usage Usage
+	if chatResp.Usage != nil {
+		usage = *chatResp.Usage
+	}
+
 	content = strings.TrimSpace(chatResp.Choices[0].Message.Content)
 
 	// Parse JSON response from LLM
@@ -131,6 +137,7 @@ func (p *OpenAICompatProvider) Summarize(ctx context.Context, content string) (*
 		return &Summary{
 			Tag:         "错误",
 			Description: "AI响应中未找到JSON",
+			Usage:       usage,
 		}, nil
 	}
 
@@ -140,9 +147,12 @@ func (p *OpenAICompatProvider) Summarize(ctx context.Context, content string) (*
 		return &Summary{
 			Tag:         "错误",
 			Description: "AI响应解析失败",
+			Usage:       usage,
 		}, nil
 	}
 
+	summary.Usage = usage
+
 	// Validate and sanitize
 	if summary.Tag == "" {
 		summary.Tag = "未知"
@@ -8,6 +8,14 @@ import (
 type Summary struct {
 	Tag         string `json:"tag"`         // 2-4 character status tag (完毕、进行、需输入、需选择、错误、等待)
 	Description string `json:"description"` // Brief description of current state
+	Usage       Usage  `json:"-"`           // Token usage reported by the API for this request
+}
+
+// Usage holds token counts reported by the API
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // Provider defines the interface for LLM providers
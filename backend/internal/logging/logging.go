@@ -0,0 +1,170 @@
+// Package logging configures log/slog for the server: text or JSON output, a global
+// level with per-subsystem overrides, secret masking, request IDs and an optional
+// rotating log file
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Options configures the root logger
+type Options struct {
+	Level      string            // debug, info, warn, error (default info)
+	Format     string            // text or json (default text)
+	File       string            // Also write to this file; relative paths are under Dir
+	Dir        string            // Base directory for a relative File
+	MaxSizeMB  int               // Rotate the file beyond this size (default 10)
+	MaxBackups int               // Rotated files to keep (default 3)
+	Subsystems map[string]string // Per-subsystem level overrides
+}
+
+// state is the active root handler and levels, swapped atomically by Setup
+type state struct {
+	handler    slog.Handler
+	level      slog.Level
+	subsystems map[string]slog.Level
+	closer     io.Closer
+}
+
+var current atomic.Pointer[state]
+
+func init() {
+	current.Store(&state{
+		handler: slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: maskSecrets}),
+		level:   slog.LevelInfo,
+	})
+}
+
+// Setup replaces the root logger; loggers from For pick up the change immediately
+// It also routes slog.Default and the standard log package through the new handler
+func Setup(opts Options) error {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+	subsystems := make(map[string]slog.Level, len(opts.Subsystems))
+	for name, l := range opts.Subsystems {
+		if subsystems[name], err = ParseLevel(l); err != nil {
+			return fmt.Errorf("subsystem %s: %w", name, err)
+		}
+	}
+
+	var out io.Writer = os.Stderr
+	var closer io.Closer
+	if opts.File != "" {
+		path := opts.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(opts.Dir, path)
+		}
+		f, err := openRotatingFile(path, opts.MaxSizeMB, opts.MaxBackups)
+		if err != nil {
+			return fmt.Errorf("log file: %w", err)
+		}
+		out = io.MultiWriter(os.Stderr, f)
+		closer = f
+	}
+
+	// Levels are filtered per subsystem in Enabled, so the root handler accepts everything
+	hopts := &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: maskSecrets}
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(out, hopts)
+	case "json":
+		handler = slog.NewJSONHandler(out, hopts)
+	default:
+		return fmt.Errorf("unknown log format %q (use text or json)", opts.Format)
+	}
+
+	old := current.Swap(&state{handler: handler, level: level, subsystems: subsystems, closer: closer})
+	slog.SetDefault(slog.New(&subsystemHandler{}))
+	if old != nil && old.closer != nil {
+		old.closer.Close()
+	}
+	return nil
+}
+
+// ParseLevel converts a level name to a slog.Level (empty means info)
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (use debug, info, warn or error)", s)
+}
+
+// For returns a logger tagged with subsystem=name
+func For(name string) *slog.Logger {
+	return slog.New(&subsystemHandler{subsystem: name})
+}
+
+// subsystemHandler forwards to the current root handler, so package-level loggers
+// created before Setup still honour its format, output and levels
+type subsystemHandler struct {
+	subsystem string
+	ops       []func(slog.Handler) slog.Handler // Deferred WithAttrs/WithGroup calls
+}
+
+func (h *subsystemHandler) Enabled(_ context.Context, level slog.Level) bool {
+	s := current.Load()
+	if l, ok := s.subsystems[h.subsystem]; ok {
+		return level >= l
+	}
+	return level >= s.level
+}
+
+func (h *subsystemHandler) Handle(ctx context.Context, r slog.Record) error {
+	handler := current.Load().handler
+	if h.subsystem != "" {
+		handler = handler.WithAttrs([]slog.Attr{slog.String("subsystem", h.subsystem)})
+	}
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return handler.Handle(ctx, r)
+}
+
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *subsystemHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &subsystemHandler{subsystem: h.subsystem, ops: append(ops, op)}
+}
+
+// secretKeys are attribute keys whose values are never written
+var secretKeys = map[string]bool{
+	"pin": true, "token": true, "password": true, "api_key": true, "apikey": true,
+	"secret": true, "authorization": true, "cookie": true, "vapid_private_key": true,
+	"p256dh": true, "auth": true, "metrics_token": true,
+}
+
+// maskSecrets replaces the value of any attribute named like a secret
+func maskSecrets(_ []string, a slog.Attr) slog.Attr {
+	if secretKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, "****")
+	}
+	return a
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Rotation defaults
+const (
+	defaultMaxSizeMB  = 10
+	defaultMaxBackups = 3
+)
+
+// rotatingFile is an append-only log file renamed to .1, .2, ... when it grows too large
+type rotatingFile struct {
+	path    string
+	maxSize int64
+	backups int
+
+	f    *os.File
+	size int64
+	mu   sync.Mutex
+}
+
+func openRotatingFile(path string, maxSizeMB, backups int) (*rotatingFile, error) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultMaxSizeMB
+	}
+	if backups <= 0 {
+		backups = defaultMaxBackups
+	}
+	rf := &rotatingFile{path: path, maxSize: int64(maxSizeMB) * 1024 * 1024, backups: backups}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.f = f
+	rf.size = info.Size()
+	return nil
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.f == nil {
+		return 0, os.ErrClosed
+	}
+	if rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// rotate shifts path.N-1 to path.N ... path to path.1 and reopens path
+// Caller must hold rf.mu
+func (rf *rotatingFile) rotate() error {
+	rf.f.Close()
+	for i := rf.backups - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", rf.path, i), fmt.Sprintf("%s.%d", rf.path, i+1))
+	}
+	_ = os.Rename(rf.path, rf.path+".1")
+	return rf.open()
+}
+
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return nil
+	}
+	err := rf.f.Close()
+	rf.f = nil
+	return err
+}
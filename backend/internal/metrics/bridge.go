@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Metrics updated directly by the packages that own the events
+// Gauges derived from live state (sessions, PTY instances, connections) are registered in main with NewGaugeFunc
+var (
+	SessionBytesIn = NewCounterVec("winterm_session_bytes_in_total",
+		"Bytes written to a session's PTY from clients", "session")
+	SessionBytesOut = NewCounterVec("winterm_session_bytes_out_total",
+		"Bytes read from a session's PTY and broadcast to clients", "session")
+	PTYRestarts = NewCounter("winterm_pty_restarts_total",
+		"PTY instances started again after the previous one exited on its own")
+	DroppedFrames = NewCounterVec("winterm_dropped_frames_total",
+		"Frames dropped because a channel was full", "direction")
+	MonitorAnalyses = NewCounterVec("winterm_monitor_analyses_total",
+		"AI monitor analysis runs by outcome", "result")
+	LLMRequestDuration = NewHistogram("winterm_llm_request_duration_seconds",
+		"LLM summarize request latency", []float64{0.25, 0.5, 1, 2, 5, 10, 20, 30, 60})
+	LLMErrors = NewCounter("winterm_llm_errors_total",
+		"LLM summarize requests that failed")
+	Notifications = NewCounterVec("winterm_notifications_total",
+		"Notifications delivered per channel by result", "channel", "result")
+	AuthFailures = NewCounterVec("winterm_auth_failures_total",
+		"Rejected authentication attempts by credential kind", "kind")
+)
+
+// bearerToken is the token /metrics requires; empty disables the endpoint
+var bearerToken atomic.Value
+
+// SetToken sets the bearer token required by Handler; it can be changed at runtime
+func SetToken(t string) {
+	bearerToken.Store(t)
+}
+
+// Handler serves all metrics in Prometheus text format
+// Requests must present the SetToken token as a Bearer token; an empty token disables the endpoint
+func Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, _ := bearerToken.Load().(string)
+		if token == "" {
+			http.Error(w, "metrics disabled: set metrics_token in runtime.json or WINTERM_METRICS_TOKEN", http.StatusNotFound)
+			return
+		}
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			AuthFailures.With("metrics").Inc()
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "invalid metrics token", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteAll(w)
+	}
+}
@@ -0,0 +1,269 @@
+// Package metrics implements the few Prometheus metric types the bridge needs
+// and renders them in the text exposition format, without a client library
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// collector writes one metric family
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]collector{}
+)
+
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[c.name()]; dup {
+		panic("metrics: duplicate metric " + c.name())
+	}
+	registry[c.name()] = c
+}
+
+// WriteAll renders every registered metric, sorted by name
+func WriteAll(w io.Writer) {
+	registryMu.Lock()
+	cs := make([]collector, 0, len(registry))
+	for _, c := range registry {
+		cs = append(cs, c)
+	}
+	registryMu.Unlock()
+
+	sort.Slice(cs, func(i, j int) bool { return cs[i].name() < cs[j].name() })
+	for _, c := range cs {
+		c.write(w)
+	}
+}
+
+// desc holds the name, help text and label names shared by all metric types
+type desc struct {
+	fqName string
+	help   string
+	labels []string
+}
+
+func (d *desc) name() string { return d.fqName }
+
+func (d *desc) header(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.fqName, escapeHelp(d.help), d.fqName, kind)
+}
+
+// labelString renders {a="x",b="y"} for the given values, plus an optional extra pair
+func (d *desc) labelString(values []string, extra ...string) string {
+	if len(d.labels) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range d.labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, l, escapeLabel(values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, extra[i], escapeLabel(extra[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// labelKey joins label values into a map key
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// Counter is a monotonically increasing integer
+type Counter struct {
+	v atomic.Uint64
+}
+
+// Inc adds one
+func (c *Counter) Inc() { c.v.Add(1) }
+
+// Add adds n
+func (c *Counter) Add(n uint64) { c.v.Add(n) }
+
+// Value returns the current count
+func (c *Counter) Value() uint64 { return c.v.Load() }
+
+// CounterVec is a counter family partitioned by labels
+type CounterVec struct {
+	desc
+	mu     sync.RWMutex
+	values map[string]*labeledCounter
+}
+
+type labeledCounter struct {
+	Counter
+	labels []string
+}
+
+// NewCounter registers an unlabeled counter
+func NewCounter(name, help string) *Counter {
+	return NewCounterVec(name, help).With()
+}
+
+// NewCounterVec registers a counter family with the given label names
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{desc: desc{fqName: name, help: help, labels: labels}, values: map[string]*labeledCounter{}}
+	register(v)
+	return v
+}
+
+// With returns the counter for the given label values, creating it on first use
+func (v *CounterVec) With(values ...string) *Counter {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.fqName, len(v.labels), len(values)))
+	}
+	key := labelKey(values)
+	v.mu.RLock()
+	c, ok := v.values[key]
+	v.mu.RUnlock()
+	if ok {
+		return &c.Counter
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok = v.values[key]; !ok {
+		c = &labeledCounter{labels: append([]string(nil), values...)}
+		v.values[key] = c
+	}
+	return &c.Counter
+}
+
+// Delete drops the series with the given label values (e.g. for a removed session)
+func (v *CounterVec) Delete(values ...string) {
+	v.mu.Lock()
+	delete(v.values, labelKey(values))
+	v.mu.Unlock()
+}
+
+func (v *CounterVec) write(w io.Writer) {
+	v.header(w, "counter")
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, key := range sortedKeys(v.values) {
+		c := v.values[key]
+		fmt.Fprintf(w, "%s%s %d\n", v.fqName, v.labelString(c.labels), c.Value())
+	}
+}
+
+// gaugeFunc is a gauge whose values are computed at scrape time
+type gaugeFunc struct {
+	desc
+	fn func() map[string]float64 // Keyed by the single label value, or "" when unlabeled
+}
+
+// NewGaugeFunc registers a gauge computed by fn on every scrape
+func NewGaugeFunc(name, help string, fn func() float64) {
+	register(&gaugeFunc{desc: desc{fqName: name, help: help}, fn: func() map[string]float64 {
+		return map[string]float64{"": fn()}
+	}})
+}
+
+// NewGaugeVecFunc registers a gauge with one label, computed by fn on every scrape
+func NewGaugeVecFunc(name, help, label string, fn func() map[string]float64) {
+	register(&gaugeFunc{desc: desc{fqName: name, help: help, labels: []string{label}}, fn: fn})
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	g.header(w, "gauge")
+	values := g.fn()
+	for _, key := range sortedKeys(values) {
+		var labels string
+		if len(g.labels) > 0 {
+			labels = g.labelString([]string{key})
+		}
+		fmt.Fprintf(w, "%s%s %s\n", g.fqName, labels, formatFloat(values[key]))
+	}
+}
+
+// Histogram counts observations into cumulative buckets
+type Histogram struct {
+	desc
+	buckets []float64 // Upper bounds, ascending
+	mu      sync.Mutex
+	counts  []uint64 // Per bucket, non-cumulative; last entry is +Inf
+	sum     float64
+	count   uint64
+}
+
+// NewHistogram registers a histogram with the given ascending bucket upper bounds
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := &Histogram{
+		desc:    desc{fqName: name, help: help},
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)+1),
+	}
+	register(h)
+	return h
+}
+
+// Observe records one value
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.header(w, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var cumulative uint64
+	for i, upper := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.fqName, h.labelString(nil, "le", formatFloat(upper)), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", h.fqName, h.labelString(nil, "le", "+Inf"), h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", h.fqName, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count %d\n", h.fqName, h.count)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
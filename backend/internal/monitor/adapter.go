@@ -1,5 +1,7 @@
 package monitor
 
+import "time"
+
 // SessionLister provides session listing capability
 type SessionLister interface {
 	ListAllForMonitor() []SessionInfo
@@ -10,6 +12,11 @@ type Broadcaster interface {
 	BroadcastToSession(sessionID string, data []byte)
 }
 
+// ActivityTracker reports client presence and input activity
+type ActivityTracker interface {
+	ClientActivity(sessionID string) (clients int, lastInput time.Time)
+}
+
 // RegistryAdapter adapts session.Registry + pty.Manager into a SessionProvider
 type RegistryAdapter struct {
 	lister      SessionLister
@@ -32,6 +39,14 @@ func (a *RegistryAdapter) BroadcastToSession(sessionID string, data []byte) {
 	a.broadcaster.BroadcastToSession(sessionID, data)
 }
 
+// ClientActivity reports attached clients if the broadcaster tracks them
+func (a *RegistryAdapter) ClientActivity(sessionID string) (int, time.Time) {
+	if t, ok := a.broadcaster.(ActivityTracker); ok {
+		return t.ClientActivity(sessionID)
+	}
+	return 0, time.Time{}
+}
+
 // NoBroadcaster is a no-op broadcaster for when no WebSocket broadcast is needed
 type NoBroadcaster struct{}
 
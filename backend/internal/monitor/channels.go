@@ -0,0 +1,145 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+
+	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/notify"
+)
+
+// UpdateNotifyChannels rebuilds the notification channels from configuration
+// Returns an error (and keeps the previous channels) if any enabled channel is invalid
+func (s *Service) UpdateNotifyChannels(cfgs []config.NotifyChannelConfig) error {
+	channels, err := buildChannels(cfgs)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.channelConfigs = cfgs
+	s.channels = channels
+	s.mu.Unlock()
+	return nil
+}
+
+// GetNotifyChannels returns the notification channel configuration
+func (s *Service) GetNotifyChannels() []config.NotifyChannelConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]config.NotifyChannelConfig, len(s.channelConfigs))
+	copy(out, s.channelConfigs)
+	return out
+}
+
+// TestNotifyChannel sends a test notification through one channel, enabled or not
+func (s *Service) TestNotifyChannel(ctx context.Context, name string) error {
+	if name == notify.EmailChannel {
+		return s.emailSender.Test()
+	}
+	if name == notify.WebPushChannel {
+		s.mu.RLock()
+		push := s.webPush
+		s.mu.RUnlock()
+		if push == nil {
+			return fmt.Errorf("web push is not available")
+		}
+		return push.Send(ctx, notify.TestNotification())
+	}
+
+	s.mu.RLock()
+	var cfg *config.NotifyChannelConfig
+	for i := range s.channelConfigs {
+		if s.channelConfigs[i].Name == name {
+			c := s.channelConfigs[i]
+			cfg = &c
+			break
+		}
+	}
+	s.mu.RUnlock()
+
+	if cfg == nil {
+		return fmt.Errorf("channel %q not found", name)
+	}
+	n, err := notify.Build(*cfg)
+	if err != nil {
+		return err
+	}
+	return n.Send(ctx, notify.TestNotification())
+}
+
+// activeNotifiers returns all channels that should receive notifications
+func (s *Service) activeNotifiers() []notify.Notifier {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]notify.Notifier, 0, len(s.channels)+1)
+	if s.emailSender.IsEnabled() {
+		out = append(out, s.emailNotifier)
+	}
+	if s.webPush != nil && hasPushSubscriptions() {
+		out = append(out, s.webPush)
+	}
+	out = append(out, s.channels...)
+	return out
+}
+
+// hasPushSubscriptions reports whether any browser has registered for push
+func hasPushSubscriptions() bool {
+	cfg := config.GetWebPushConfig()
+	return cfg != nil && len(cfg.Subscriptions) > 0
+}
+
+// loadWebPush sets up the browser push channel, generating VAPID keys on first run
+func (s *Service) loadWebPush() {
+	cfg, err := config.EnsureVAPIDKeys(notify.GenerateVAPIDKeys)
+	if err != nil {
+		logger.Error("Failed to initialize web push keys", "err", err)
+		return
+	}
+	push, err := notify.NewWebPushNotifier(cfg)
+	if err != nil {
+		logger.Warn("Invalid web push config", "err", err)
+		return
+	}
+	s.mu.Lock()
+	s.webPush = push
+	s.mu.Unlock()
+}
+
+// WebPushPublicKey returns the VAPID public key browsers subscribe with
+func (s *Service) WebPushPublicKey() string {
+	cfg := config.GetWebPushConfig()
+	if cfg == nil {
+		return ""
+	}
+	return cfg.VAPIDPublicKey
+}
+
+// buildChannels creates notifiers for all enabled channel configs
+func buildChannels(cfgs []config.NotifyChannelConfig) ([]notify.Notifier, error) {
+	seen := make(map[string]bool)
+	var out []notify.Notifier
+	for _, c := range cfgs {
+		if seen[c.Name] {
+			return nil, fmt.Errorf("duplicate channel name %q", c.Name)
+		}
+		seen[c.Name] = true
+
+		n, err := notify.Build(c)
+		if err != nil {
+			return nil, err
+		}
+		if c.Enabled {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+// loadNotifyChannels applies the channels saved in the config file
+func (s *Service) loadNotifyChannels() {
+	if err := s.UpdateNotifyChannels(config.GetNotifyChannels()); err != nil {
+		logger.Warn("Invalid notification channel config", "err", err)
+	}
+}
@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+
+	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/email"
+	"winterm-bridge/internal/notify"
+)
+
+func TestBuildChannels(t *testing.T) {
+	hook := func(name string, enabled bool) config.NotifyChannelConfig {
+		return config.NotifyChannelConfig{Name: name, Type: notify.TypeWebhook, Enabled: enabled, URL: "https://example.com/hook"}
+	}
+	tests := []struct {
+		name  string
+		cfgs  []config.NotifyChannelConfig
+		names []string // Active channels, in order
+		err   string   // Substring of the error, empty if valid
+	}{
+		{"none", nil, nil, ""},
+		{"disabled are skipped", []config.NotifyChannelConfig{hook("a", true), hook("b", false), hook("c", true)}, []string{"a", "c"}, ""},
+		{"duplicate name", []config.NotifyChannelConfig{hook("a", true), hook("a", false)}, nil, "duplicate channel name"},
+		{"disabled still validated", []config.NotifyChannelConfig{{Name: "x", Type: notify.TypeWebhook}}, nil, "url is required"},
+		{"reserved name", []config.NotifyChannelConfig{hook(notify.EmailChannel, true)}, nil, "reserved"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildChannels(tt.cfgs)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("buildChannels() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, n := range got {
+				names = append(names, n.Name())
+			}
+			if strings.Join(names, ",") != strings.Join(tt.names, ",") {
+				t.Fatalf("active channels = %v, want %v", names, tt.names)
+			}
+		})
+	}
+}
+
+func TestNotifiersByName(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	svc := &Service{
+		emailSender: &email.Sender{},
+		channels:    []notify.Notifier{&fakeNotifier{name: "a"}, &fakeNotifier{name: "b"}},
+	}
+	tests := []struct {
+		name  string
+		names []string
+		want  string
+	}{
+		{"all", nil, "a,b"},
+		{"one", []string{"b"}, "b"},
+		{"unknown", []string{"x"}, ""},
+		{"some unknown", []string{"x", "a"}, "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, n := range svc.notifiersByName(tt.names) {
+				got = append(got, n.Name())
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Fatalf("notifiersByName(%v) = %v, want %s", tt.names, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package monitor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"winterm-bridge/internal/config"
+)
+
+// DefaultHistorySize is the number of summary transitions kept per session
+const DefaultHistorySize = 100
+
+// HistoryEntry is a single summary transition in a session's status timeline
+type HistoryEntry struct {
+	Timestamp   int64  `json:"timestamp"`
+	Tag         string `json:"tag"`
+	Description string `json:"description"`
+	Hash        string `json:"hash"` // Hash of the terminal content that produced this summary
+}
+
+// summaryHistory is a fixed-capacity ring buffer of summary transitions
+type summaryHistory struct {
+	entries []HistoryEntry
+	start   int // Index of the oldest entry
+	count   int
+}
+
+func newSummaryHistory(size int) *summaryHistory {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &summaryHistory{entries: make([]HistoryEntry, size)}
+}
+
+// add appends an entry if it differs from the latest one, overwriting the oldest when full
+// Returns true if the entry was recorded
+func (h *summaryHistory) add(e HistoryEntry) bool {
+	if last, ok := h.last(); ok && last.Tag == e.Tag && last.Description == e.Description {
+		return false
+	}
+	if h.count < len(h.entries) {
+		h.entries[(h.start+h.count)%len(h.entries)] = e
+		h.count++
+	} else {
+		h.entries[h.start] = e
+		h.start = (h.start + 1) % len(h.entries)
+	}
+	return true
+}
+
+// last returns the newest entry
+func (h *summaryHistory) last() (HistoryEntry, bool) {
+	if h.count == 0 {
+		return HistoryEntry{}, false
+	}
+	return h.entries[(h.start+h.count-1)%len(h.entries)], true
+}
+
+// list returns the entries from oldest to newest
+func (h *summaryHistory) list() []HistoryEntry {
+	out := make([]HistoryEntry, 0, h.count)
+	for i := 0; i < h.count; i++ {
+		out = append(out, h.entries[(h.start+i)%len(h.entries)])
+	}
+	return out
+}
+
+// resize returns a copy of the history with a new capacity, keeping the newest entries
+func (h *summaryHistory) resize(size int) *summaryHistory {
+	nh := newSummaryHistory(size)
+	entries := h.list()
+	if len(entries) > len(nh.entries) {
+		entries = entries[len(entries)-len(nh.entries):]
+	}
+	copy(nh.entries, entries)
+	nh.count = len(entries)
+	return nh
+}
+
+// historyFileMu serializes writes to the history file
+var historyFileMu sync.Mutex
+
+// historyPath returns the path of the persisted summary history file
+func historyPath() string {
+	return filepath.Join(config.DefaultConfigDir(), "summary_history.json")
+}
+
+// loadHistory reads persisted summary history from disk
+func loadHistory(size int) map[string]*summaryHistory {
+	out := make(map[string]*summaryHistory)
+
+	data, err := os.ReadFile(historyPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error("Failed to read summary history", "err", err)
+		}
+		return out
+	}
+
+	var stored map[string][]HistoryEntry
+	if err := json.Unmarshal(data, &stored); err != nil {
+		logger.Error("Failed to parse summary history", "err", err)
+		return out
+	}
+
+	for sessionID, entries := range stored {
+		h := newSummaryHistory(size)
+		for _, e := range entries {
+			h.add(e)
+		}
+		out[sessionID] = h
+	}
+	return out
+}
+
+// saveHistory writes summary history to disk atomically
+func saveHistory(stored map[string][]HistoryEntry) error {
+	historyFileMu.Lock()
+	defer historyFileMu.Unlock()
+
+	if err := os.MkdirAll(config.DefaultConfigDir(), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	tmp := historyPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, historyPath())
+}
@@ -0,0 +1,46 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"winterm-bridge/internal/events"
+	"winterm-bridge/internal/llm"
+)
+
+func TestDeletedSessionHistoryIsDropped(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	s := NewService(&fakeSessions{})
+	s.config.PersistHistory = true
+	hub := events.NewHub()
+	defer hub.Close()
+	s.SetEventHub(hub)
+
+	at := time.Now()
+	s.recordHistory("kept", "h1", &llm.Summary{Tag: "完毕"}, at)
+	s.recordHistory("deleted", "h2", &llm.Summary{Tag: "错误"}, at)
+	if stored := loadHistory(DefaultHistorySize); len(stored) != 2 {
+		t.Fatalf("persisted history for %d sessions, want 2", len(stored))
+	}
+
+	// The monitor subscribes in the background
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.SubscriberCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	hub.Publish(events.TypeSessionDeleted, "deleted", events.SessionData{})
+	for len(s.GetHistory("deleted")) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("history kept after the session was deleted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stored := loadHistory(DefaultHistorySize)
+	if _, ok := stored["deleted"]; ok || len(stored) != 1 {
+		t.Fatalf("persisted history after delete: %v", stored)
+	}
+	if got := s.GetHistory("kept"); len(got) != 1 {
+		t.Fatalf("history of the remaining session: %+v", got)
+	}
+}
@@ -0,0 +1,195 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"winterm-bridge/internal/config"
+)
+
+// redactedMarker replaces detected secrets in content sent to the LLM
+const redactedMarker = "[REDACTED]"
+
+// Entropy detector thresholds: long tokens with high Shannon entropy look like keys
+const (
+	entropyMinLength = 24
+	entropyThreshold = 4.0 // bits per character
+)
+
+// redactRule is a named pattern whose matches are replaced before content leaves the machine
+type redactRule struct {
+	name        string
+	re          *regexp.Regexp
+	replacement string // Replacement template (may reference groups), defaults to redactedMarker
+}
+
+// builtinRules are always applied, in order
+var builtinRules = []redactRule{
+	{
+		name: "private_key",
+		// Complete blocks, or a BEGIN line whose END scrolled out of the captured lines
+		re: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----(?s:.*?)(-----END [A-Z0-9 ]*PRIVATE KEY-----|\z)`),
+	},
+	{
+		name: "aws_access_key",
+		re:   regexp.MustCompile(`\b(AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16}\b`),
+	},
+	{
+		name:        "aws_secret_key",
+		re:          regexp.MustCompile(`(?i)(aws_secret_access_key|aws_session_token)(\s*[=:]\s*)["']?[A-Za-z0-9/+=]{16,}["']?`),
+		replacement: "${1}${2}" + redactedMarker,
+	},
+	{
+		name: "jwt",
+		re:   regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]+`),
+	},
+	{
+		name:        "bearer_token",
+		re:          regexp.MustCompile(`(?i)\b(bearer|basic)(\s+)[A-Za-z0-9\-._~+/]{8,}=*`),
+		replacement: "${1}${2}" + redactedMarker,
+	},
+	{
+		name:        "url_credentials",
+		re:          regexp.MustCompile(`([A-Za-z][A-Za-z0-9+.\-]*://)[^/\s:@]+:[^/\s@]+@`),
+		replacement: "${1}" + redactedMarker + "@",
+	},
+	{
+		name:        "secret_assignment",
+		re:          regexp.MustCompile(`(?i)\b([A-Z0-9_.\-]*(?:PASSWORD|PASSWD|SECRET|TOKEN|API_?KEY|ACCESS_?KEY|PRIVATE_?KEY|CREDENTIALS?)[A-Z0-9_.\-]*)(\s*[=:]\s*)("[^"]*"|'[^']*'|\S+)`),
+		replacement: "${1}${2}" + redactedMarker,
+	},
+}
+
+// entropyCandidate matches tokens that could be random keys
+var entropyCandidate = regexp.MustCompile(`[A-Za-z0-9+/=_\-]{` + fmt.Sprint(entropyMinLength) + `,}`)
+
+// RedactionCount reports how many matches a rule replaced
+type RedactionCount struct {
+	Rule  string `json:"rule"`
+	Count int    `json:"count"`
+}
+
+// Redactor removes secrets from terminal content before it is sent to the LLM
+type Redactor struct {
+	rules []redactRule
+}
+
+// NewRedactor builds a redactor from the built-in detectors plus user-supplied regexes
+func NewRedactor(patterns []string) (*Redactor, error) {
+	rules := make([]redactRule, 0, len(builtinRules)+len(patterns))
+	rules = append(rules, builtinRules...)
+	for i, p := range patterns {
+		re, err := config.CompileRedactPattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		rules = append(rules, redactRule{name: fmt.Sprintf("custom_%d", i+1), re: re})
+	}
+	return &Redactor{rules: rules}, nil
+}
+
+// Redact returns content with secrets replaced and per-rule replacement counts
+func (r *Redactor) Redact(content string) (string, []RedactionCount) {
+	var counts []RedactionCount
+
+	for _, rule := range r.rules {
+		var n int
+		content, n = rule.apply(content)
+		if n > 0 {
+			counts = append(counts, RedactionCount{Rule: rule.name, Count: n})
+		}
+	}
+
+	n := 0
+	content = entropyCandidate.ReplaceAllStringFunc(content, func(match string) string {
+		if match == redactedMarker || !looksRandom(match) {
+			return match
+		}
+		n++
+		return redactedMarker
+	})
+	if n > 0 {
+		counts = append(counts, RedactionCount{Rule: "high_entropy", Count: n})
+	}
+
+	return content, counts
+}
+
+// apply replaces every match of the rule in content and returns how many it replaced
+// The replacement is expanded from each match in place, so patterns that depend on the text
+// around the match (\b, ^, $) behave as they did when matching. Empty matches are skipped
+func (rule redactRule) apply(content string) (string, int) {
+	replacement := rule.replacement
+	if replacement == "" {
+		replacement = redactedMarker
+	}
+	var out []byte
+	last, n := 0, 0
+	for _, m := range rule.re.FindAllStringSubmatchIndex(content, -1) {
+		if m[0] == m[1] {
+			continue
+		}
+		out = append(out, content[last:m[0]]...)
+		out = rule.re.ExpandString(out, replacement, content, m)
+		last = m[1]
+		n++
+	}
+	if n == 0 {
+		return content, 0
+	}
+	return string(append(out, content[last:]...)), n
+}
+
+// looksRandom reports whether a token has the character mix and entropy of a generated secret
+// Paths and words (all letters, or separated by / - _) are excluded to avoid redacting normal output
+func looksRandom(s string) bool {
+	var hasUpper, hasLower, hasDigit bool
+	for _, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			hasUpper = true
+		case c >= 'a' && c <= 'z':
+			hasLower = true
+		case c >= '0' && c <= '9':
+			hasDigit = true
+		}
+	}
+	if !hasDigit || !(hasUpper || hasLower) {
+		return false
+	}
+	// Long hex strings (commit hashes, checksums) are common and harmless in terminal output
+	if isHex(s) {
+		return false
+	}
+	if strings.Count(s, "/") > 1 || strings.Count(s, "-") > 3 {
+		return false
+	}
+	return shannonEntropy(s) >= entropyThreshold
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	freq := make(map[rune]int)
+	total := 0
+	for _, c := range s {
+		freq[c]++
+		total++
+	}
+	var h float64
+	for _, n := range freq {
+		p := float64(n) / float64(total)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,292 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/events"
+	"winterm-bridge/internal/llm"
+	"winterm-bridge/internal/notify"
+)
+
+// ruleState tracks one routing rule's progress for a session
+type ruleState struct {
+	tag       string    // Tag the rule is currently tracking
+	since     time.Time // When the tag was first seen
+	firstSent time.Time // First successful delivery (zero = not yet sent)
+	lastSent  time.Time // Last successful delivery, for repeats
+	escalated bool
+	waiting   bool // Rule still has a send, repeat or escalation ahead
+}
+
+// UpdateNotifyRules validates and applies notification routing rules
+func (s *Service) UpdateNotifyRules(rules []config.NotifyRule) error {
+	if err := ValidateNotifyRules(rules); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.rules = rules
+	// Rule progress is keyed by name; start over so renamed or edited rules don't inherit it
+	for _, state := range s.states {
+		state.rules = nil
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// GetNotifyRules returns the notification routing rules
+func (s *Service) GetNotifyRules() []config.NotifyRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]config.NotifyRule, len(s.rules))
+	copy(out, s.rules)
+	return out
+}
+
+// ValidateNotifyRules checks rule names, glob patterns and quiet hours
+func ValidateNotifyRules(rules []config.NotifyRule) error {
+	seen := make(map[string]bool)
+	for _, r := range rules {
+		if r.Name == "" {
+			return fmt.Errorf("rule name is required")
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("duplicate rule name %q", r.Name)
+		}
+		seen[r.Name] = true
+
+		if _, err := path.Match(r.Sessions, ""); err != nil {
+			return fmt.Errorf("rule %q: invalid sessions pattern: %w", r.Name, err)
+		}
+		for _, t := range r.Tags {
+			if _, err := path.Match(t, ""); err != nil {
+				return fmt.Errorf("rule %q: invalid tag pattern %q: %w", r.Name, t, err)
+			}
+		}
+		if r.Delay < 0 || r.RepeatInterval < 0 || r.EscalateAfter < 0 {
+			return fmt.Errorf("rule %q: durations must not be negative", r.Name)
+		}
+		if r.EscalateAfter > 0 && len(r.EscalateTo) == 0 {
+			return fmt.Errorf("rule %q: escalate_to is required with escalate_after", r.Name)
+		}
+		if q := r.QuietHours; q != nil {
+			if _, err := parseClock(q.Start); err != nil {
+				return fmt.Errorf("rule %q: quiet_hours.start: %w", r.Name, err)
+			}
+			if _, err := parseClock(q.End); err != nil {
+				return fmt.Errorf("rule %q: quiet_hours.end: %w", r.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadNotifyRules applies the rules saved in the config file
+func (s *Service) loadNotifyRules() {
+	if err := s.UpdateNotifyRules(config.GetNotifyRules()); err != nil {
+		logger.Warn("Invalid notification rule config", "err", err)
+	}
+}
+
+// evaluateRules runs every enabled rule against the session's current summary
+// While the user is typing, delays restart and repeats and escalations are held
+func (s *Service) evaluateRules(sess SessionInfo, summary *llm.Summary, state *sessionState, rules []config.NotifyRule, now time.Time, typing bool) {
+	for _, r := range rules {
+		if !r.Enabled {
+			continue
+		}
+
+		s.mu.Lock()
+		if state.rules == nil {
+			state.rules = make(map[string]*ruleState)
+		}
+		if !ruleMatches(r, sess, summary.Tag) {
+			// Leaving the matched state re-arms the rule
+			delete(state.rules, r.Name)
+			s.mu.Unlock()
+			continue
+		}
+		rs, ok := state.rules[r.Name]
+		if !ok || rs.tag != summary.Tag {
+			rs = &ruleState{tag: summary.Tag, since: now}
+			state.rules[r.Name] = rs
+		}
+		if typing {
+			if rs.firstSent.IsZero() {
+				rs.since = now
+			}
+			s.mu.Unlock()
+			continue
+		}
+		snapshot := *rs
+		attended := state.clients > 0 || (!snapshot.firstSent.IsZero() && state.lastAttach.After(snapshot.firstSent))
+		s.mu.Unlock()
+
+		if now.Sub(snapshot.since) < s.ruleDelay(r) || inQuietHours(r.QuietHours, now) {
+			continue
+		}
+
+		send := snapshot.firstSent.IsZero() ||
+			(r.RepeatInterval > 0 && now.Sub(snapshot.lastSent) >= time.Duration(r.RepeatInterval)*time.Second)
+		if send && s.sendRule(sess, summary, r.Name, r.Channels, now) {
+			s.mu.Lock()
+			if snapshot.firstSent.IsZero() {
+				rs.firstSent = now
+			}
+			rs.lastSent = now
+			s.mu.Unlock()
+		}
+
+		// Escalate once if nobody attached within EscalateAfter of the first notification
+		if r.EscalateAfter > 0 && !snapshot.escalated && !snapshot.firstSent.IsZero() && !attended &&
+			now.Sub(snapshot.firstSent) >= time.Duration(r.EscalateAfter)*time.Minute {
+			s.sendRule(sess, summary, r.Name+" escalation", r.EscalateTo, now)
+			s.mu.Lock()
+			rs.escalated = true
+			s.mu.Unlock()
+		}
+
+		s.mu.Lock()
+		rs.waiting = rs.firstSent.IsZero() || r.RepeatInterval > 0 || (r.EscalateAfter > 0 && !rs.escalated)
+		s.mu.Unlock()
+	}
+}
+
+// sendRule delivers a notification to the named channels (empty = all active channels)
+// Returns true if at least one channel succeeded
+func (s *Service) sendRule(sess SessionInfo, summary *llm.Summary, rule string, channels []string, now time.Time) bool {
+	notifiers := s.notifiersByName(channels)
+	if len(notifiers) == 0 {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	results := notify.SendAll(ctx, notifiers, s.buildNotification(sess, summary, now))
+
+	delivered := false
+	for _, r := range results {
+		if r.Error != "" {
+			logger.Warn("Failed to send notification", "rule", rule, "channel", r.Channel, "session", sess.ID[:8], "err", r.Error)
+			continue
+		}
+		delivered = true
+	}
+	return delivered
+}
+
+// notifiersByName returns the active notifiers with the given names (empty = all)
+func (s *Service) notifiersByName(names []string) []notify.Notifier {
+	active := s.activeNotifiers()
+	if len(names) == 0 {
+		return active
+	}
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
+	}
+	var out []notify.Notifier
+	for _, n := range active {
+		if want[n.Name()] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// ruleDelay returns how long a state must persist before the rule fires
+func (s *Service) ruleDelay(r config.NotifyRule) time.Duration {
+	if r.Delay > 0 {
+		return time.Duration(r.Delay) * time.Second
+	}
+	return s.notifyDelay()
+}
+
+// ruleMatches reports whether a rule applies to a session in the given state
+func ruleMatches(r config.NotifyRule, sess SessionInfo, tag string) bool {
+	if r.Sessions != "" {
+		titleOK, _ := path.Match(r.Sessions, sess.Title)
+		tmuxOK, _ := path.Match(r.Sessions, sess.TmuxName)
+		if !titleOK && !tmuxOK {
+			return false
+		}
+	}
+	if len(r.Tags) == 0 {
+		return notifiableTags[tag]
+	}
+	for _, t := range r.Tags {
+		if ok, _ := path.Match(t, tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// inQuietHours reports whether t falls inside the quiet window
+func inQuietHours(q *config.QuietHours, t time.Time) bool {
+	if q == nil {
+		return false
+	}
+	start, err1 := parseClock(q.Start)
+	end, err2 := parseClock(q.End)
+	if err1 != nil || err2 != nil || start == end {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return now >= start && now < end
+	}
+	// Window wraps past midnight, e.g. 22:00-07:00
+	return now >= start || now < end
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// rulesWaiting reports whether any rule has a pending send for the session
+// Caller must hold s.mu
+func (state *sessionState) rulesWaiting() bool {
+	for _, rs := range state.rules {
+		if rs.waiting {
+			return true
+		}
+	}
+	return false
+}
+
+// watchEvents records client attach/detach events for escalation decisions and drops the
+// state of deleted sessions
+func (s *Service) watchEvents(hub *events.Hub) {
+	ch := hub.Subscribe()
+	for ev := range ch {
+		if ev.Type == events.TypeSessionDeleted {
+			s.CleanupSession(ev.SessionID)
+			continue
+		}
+		if ev.Type != events.TypeClientAttached && ev.Type != events.TypeClientDetached {
+			continue
+		}
+		data, ok := ev.Data.(events.ClientData)
+		if !ok {
+			continue
+		}
+		s.mu.Lock()
+		state := s.getOrCreateStateLocked(ev.SessionID)
+		state.clients = data.Clients
+		if ev.Type == events.TypeClientAttached {
+			state.lastAttach = s.now()
+		}
+		s.mu.Unlock()
+	}
+}
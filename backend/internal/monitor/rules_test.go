@@ -0,0 +1,250 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/notify"
+)
+
+func TestInQuietHours(t *testing.T) {
+	at := func(hhmm string) time.Time {
+		c, err := time.Parse("15:04", hhmm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Date(2024, 1, 1, c.Hour(), c.Minute(), 30, 0, time.UTC)
+	}
+	day := &config.QuietHours{Start: "09:00", End: "17:00"}
+	night := &config.QuietHours{Start: "22:00", End: "07:00"}
+	tests := []struct {
+		name string
+		q    *config.QuietHours
+		at   string
+		want bool
+	}{
+		{"no window", nil, "12:00", false},
+		{"before day window", day, "08:59", false},
+		{"day window start", day, "09:00", true},
+		{"inside day window", day, "16:59", true},
+		{"day window end", day, "17:00", false},
+		{"night window start", night, "22:00", true},
+		{"before midnight", night, "23:30", true},
+		{"midnight", night, "00:00", true},
+		{"after midnight", night, "06:59", true},
+		{"night window end", night, "07:00", false},
+		{"midday outside night window", night, "12:00", false},
+		{"empty window", &config.QuietHours{Start: "08:00", End: "08:00"}, "08:00", false},
+		{"invalid start", &config.QuietHours{Start: "25:00", End: "07:00"}, "23:00", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inQuietHours(tt.q, at(tt.at)); got != tt.want {
+				t.Errorf("inQuietHours(%+v, %s) = %v, want %v", tt.q, tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	sess := SessionInfo{ID: "s1", Title: "build-api", TmuxName: "wb-1234"}
+	tests := []struct {
+		name string
+		rule config.NotifyRule
+		tag  string
+		want bool
+	}{
+		{"all sessions, notifiable tag", config.NotifyRule{}, "完毕", true},
+		{"all sessions, other tag", config.NotifyRule{}, "进行", false},
+		{"title glob", config.NotifyRule{Sessions: "build-*"}, "错误", true},
+		{"tmux name glob", config.NotifyRule{Sessions: "wb-*"}, "错误", true},
+		{"other sessions", config.NotifyRule{Sessions: "deploy*"}, "错误", false},
+		{"tag glob", config.NotifyRule{Tags: []string{"需*"}}, "需选择", true},
+		{"tag glob miss", config.NotifyRule{Tags: []string{"需*"}}, "完毕", false},
+		{"explicit non-notifiable tag", config.NotifyRule{Tags: []string{"进行"}}, "进行", true},
+		{"any tag", config.NotifyRule{Tags: []string{"*"}}, "进行", true},
+		{"session and tag", config.NotifyRule{Sessions: "build-?pi", Tags: []string{"错误", "完毕"}}, "完毕", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ruleMatches(tt.rule, sess, tt.tag); got != tt.want {
+				t.Errorf("ruleMatches(%+v, %q) = %v, want %v", tt.rule, tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+// ruleStep advances the clock, optionally attaches a client, reports a tag and checks the
+// deliveries so far on the rule's channel and the escalation channel
+type ruleStep struct {
+	after     time.Duration
+	attach    bool
+	tag       string
+	sent      int
+	escalated int
+}
+
+func TestEvaluateRules(t *testing.T) {
+	base := config.NotifyRule{Name: "r", Enabled: true, Delay: 30, Channels: []string{"fake"}}
+	with := func(edit func(*config.NotifyRule)) config.NotifyRule {
+		r := base
+		edit(&r)
+		return r
+	}
+	tests := []struct {
+		name  string
+		rule  config.NotifyRule
+		steps []ruleStep
+	}{
+		{
+			name: "delay",
+			rule: base,
+			steps: []ruleStep{
+				{0, false, "完毕", 0, 0},
+				{29 * time.Second, false, "完毕", 0, 0},
+				{time.Second, false, "完毕", 1, 0},
+				{5 * time.Minute, false, "完毕", 1, 0},
+			},
+		},
+		{
+			name: "re-arm",
+			rule: base,
+			steps: []ruleStep{
+				{0, false, "完毕", 0, 0},
+				{30 * time.Second, false, "完毕", 1, 0},
+				{10 * time.Second, false, "进行", 1, 0},
+				{10 * time.Second, false, "完毕", 1, 0},
+				{30 * time.Second, false, "完毕", 2, 0},
+				// Moving to another matching tag starts over too
+				{10 * time.Second, false, "错误", 2, 0},
+				{30 * time.Second, false, "错误", 3, 0},
+			},
+		},
+		{
+			name: "repeat",
+			rule: with(func(r *config.NotifyRule) { r.Delay, r.RepeatInterval = 10, 60 }),
+			steps: []ruleStep{
+				{0, false, "需输入", 0, 0},
+				{10 * time.Second, false, "需输入", 1, 0},
+				{59 * time.Second, false, "需输入", 1, 0},
+				{time.Second, false, "需输入", 2, 0},
+				{time.Minute, false, "需输入", 3, 0},
+			},
+		},
+		{
+			name: "escalation",
+			rule: with(func(r *config.NotifyRule) { r.Delay, r.EscalateAfter, r.EscalateTo = 10, 2, []string{"pager"} }),
+			steps: []ruleStep{
+				{0, false, "需输入", 0, 0},
+				{10 * time.Second, false, "需输入", 1, 0},
+				{119 * time.Second, false, "需输入", 1, 0},
+				{time.Second, false, "需输入", 1, 1},
+				{10 * time.Minute, false, "需输入", 1, 1},
+			},
+		},
+		{
+			name: "attaching cancels escalation",
+			rule: with(func(r *config.NotifyRule) { r.Delay, r.EscalateAfter, r.EscalateTo = 10, 2, []string{"pager"} }),
+			steps: []ruleStep{
+				{0, false, "需输入", 0, 0},
+				{10 * time.Second, false, "需输入", 1, 0},
+				{30 * time.Second, true, "需输入", 1, 0},
+				{10 * time.Minute, false, "需输入", 1, 0},
+			},
+		},
+		{
+			name: "quiet hours hold",
+			rule: with(func(r *config.NotifyRule) {
+				r.Delay, r.QuietHours = 10, &config.QuietHours{Start: "11:00", End: "12:01"}
+			}),
+			steps: []ruleStep{
+				{0, false, "完毕", 0, 0},
+				{10 * time.Second, false, "完毕", 0, 0},
+				{50 * time.Second, false, "完毕", 1, 0},
+			},
+		},
+		{
+			name: "tag glob",
+			rule: with(func(r *config.NotifyRule) { r.Tags = []string{"需*"} }),
+			steps: []ruleStep{
+				{0, false, "完毕", 0, 0},
+				{30 * time.Second, false, "完毕", 0, 0},
+				{0, false, "需选择", 0, 0},
+				{30 * time.Second, false, "需选择", 1, 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newNotifyHarness(t, "44444444-rules")
+			pager := &fakeNotifier{name: "pager"}
+			h.svc.channels = append(h.svc.channels, pager)
+			if err := h.svc.UpdateNotifyRules([]config.NotifyRule{tt.rule}); err != nil {
+				t.Fatal(err)
+			}
+			for i, step := range tt.steps {
+				if step.attach {
+					h.state.lastAttach = h.now.Add(step.after)
+				}
+				h.check(step.after, step.tag)
+				if got := h.notifier.count(); got != step.sent {
+					t.Fatalf("step %d: sent %d notifications, want %d", i, got, step.sent)
+				}
+				if got := pager.count(); got != step.escalated {
+					t.Fatalf("step %d: sent %d escalations, want %d", i, got, step.escalated)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateNotifyRules(t *testing.T) {
+	valid := config.NotifyRule{
+		Name:           "ok",
+		Sessions:       "build-*",
+		Tags:           []string{"需*"},
+		Delay:          10,
+		RepeatInterval: 60,
+		EscalateAfter:  5,
+		EscalateTo:     []string{notify.EmailChannel},
+		QuietHours:     &config.QuietHours{Start: "22:00", End: "07:00"},
+	}
+	with := func(edit func(*config.NotifyRule)) []config.NotifyRule {
+		r := valid
+		edit(&r)
+		return []config.NotifyRule{r}
+	}
+	tests := []struct {
+		name  string
+		rules []config.NotifyRule
+		err   string // Substring of the error, empty if valid
+	}{
+		{"none", nil, ""},
+		{"valid", []config.NotifyRule{valid}, ""},
+		{"no name", with(func(r *config.NotifyRule) { r.Name = "" }), "name is required"},
+		{"duplicate name", []config.NotifyRule{valid, valid}, "duplicate rule name"},
+		{"bad sessions pattern", with(func(r *config.NotifyRule) { r.Sessions = "[" }), "invalid sessions pattern"},
+		{"bad tag pattern", with(func(r *config.NotifyRule) { r.Tags = []string{"ok", "["} }), "invalid tag pattern"},
+		{"negative delay", with(func(r *config.NotifyRule) { r.Delay = -1 }), "must not be negative"},
+		{"negative repeat", with(func(r *config.NotifyRule) { r.RepeatInterval = -1 }), "must not be negative"},
+		{"escalation without channels", with(func(r *config.NotifyRule) { r.EscalateTo = nil }), "escalate_to is required"},
+		{"bad quiet start", with(func(r *config.NotifyRule) { r.QuietHours = &config.QuietHours{Start: "25:00", End: "07:00"} }), "quiet_hours.start"},
+		{"bad quiet end", with(func(r *config.NotifyRule) { r.QuietHours = &config.QuietHours{Start: "22:00", End: "7pm"} }), "quiet_hours.end"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNotifyRules(tt.rules)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("ValidateNotifyRules() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("ValidateNotifyRules() = %v, want error containing %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package monitor
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"winterm-bridge/internal/metrics"
+)
+
+const (
+	// schedulerTick is how often the scheduler looks for due sessions
+	schedulerTick = time.Second
+	// DefaultConcurrency is the default number of sessions analyzed in parallel
+	DefaultConcurrency = 4
+	// DefaultMaxIdleInterval is the default upper bound (seconds) for idle backoff
+	DefaultMaxIdleInterval = 300
+	// maxFailureBackoff caps the retry delay after consecutive failures
+	maxFailureBackoff = 10 * time.Minute
+	// idleBackoffFactor grows the interval for each unchanged capture
+	idleBackoffFactor = 1.5
+	// jitterFraction spreads next-run times by ±10% so captures don't align
+	jitterFraction = 0.1
+
+	// Circuit breaker: open after this many consecutive LLM failures
+	breakerThreshold = 5
+	// Initial and maximum time the breaker stays open
+	breakerCooldown    = 30 * time.Second
+	breakerMaxCooldown = 10 * time.Minute
+)
+
+// analysisResult is the outcome of one analyzeSession run
+type analysisResult int
+
+const (
+	resultSkipped   analysisResult = iota // Nothing captured (session gone, empty pane)
+	resultUnchanged                       // Content identical to last capture
+	resultChanged                         // Content changed and was summarized
+	resultFailed                          // LLM call failed
+)
+
+// String returns the result name used as a metrics label
+func (r analysisResult) String() string {
+	switch r {
+	case resultUnchanged:
+		return "unchanged"
+	case resultChanged:
+		return "changed"
+	case resultFailed:
+		return "failed"
+	default:
+		return "skipped"
+	}
+}
+
+// circuitBreaker stops LLM calls for a cooldown after repeated failures
+type circuitBreaker struct {
+	failures  int
+	openUntil time.Time
+	cooldown  time.Duration
+	mu        sync.Mutex
+}
+
+// allow reports whether requests may be sent
+func (cb *circuitBreaker) allow(now time.Time) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return !now.Before(cb.openUntil)
+}
+
+// success closes the breaker and resets its cooldown
+func (cb *circuitBreaker) success() {
+	cb.mu.Lock()
+	cb.failures = 0
+	cb.cooldown = 0
+	cb.mu.Unlock()
+}
+
+// failure records a failed request and opens the breaker once the threshold is reached
+func (cb *circuitBreaker) failure(now time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	if cb.failures < breakerThreshold {
+		return
+	}
+
+	// Each re-open doubles the cooldown
+	if cb.cooldown == 0 {
+		cb.cooldown = breakerCooldown
+	} else {
+		cb.cooldown *= 2
+		if cb.cooldown > breakerMaxCooldown {
+			cb.cooldown = breakerMaxCooldown
+		}
+	}
+	cb.openUntil = now.Add(cb.cooldown)
+	cb.failures = 0
+	logger.Warn("Circuit breaker open after repeated LLM failures", "cooldown", cb.cooldown.String())
+}
+
+// loop is the main monitoring loop
+// It dispatches due sessions to a bounded worker pool on every scheduler tick
+func (s *Service) loop(ctx context.Context) {
+	s.mu.RLock()
+	concurrency := s.config.Concurrency
+	s.mu.RUnlock()
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+			s.lastTick.Store(time.Now().UnixNano())
+			s.dispatch(ctx, sem, &wg)
+		}
+	}
+}
+
+// dispatch starts analysis for every session whose next run time has passed
+func (s *Service) dispatch(ctx context.Context, sem chan struct{}, wg *sync.WaitGroup) {
+	if s.checkBudget() {
+		return
+	}
+	now := time.Now()
+	if !s.breaker.allow(now) {
+		return
+	}
+
+	for _, sess := range s.sessions.GetAllSessions() {
+		// Skip ghost sessions (no tmux to capture)
+		if sess.IsGhost {
+			continue
+		}
+		cfg := s.sessionConfig(sess.ID)
+		if !cfg.Enabled {
+			continue
+		}
+		if !s.claim(sess.ID, now) {
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		default:
+			// Pool is full; leave the session due for the next tick
+			s.release(sess.ID)
+			return
+		}
+
+		wg.Add(1)
+		go func(sess SessionInfo, cfg Config) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result := s.analyzeSession(ctx, sess, cfg)
+			metrics.MonitorAnalyses.With(result.String()).Inc()
+			s.reschedule(sess.ID, cfg, result)
+		}(sess, cfg)
+	}
+}
+
+// claim marks a due session as in flight, returning false if it isn't due or already running
+func (s *Service) claim(sessionID string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.getOrCreateStateLocked(sessionID)
+	if state.nextRun.IsZero() {
+		// First sighting: spread initial captures so they don't all hit tmux at once
+		state.nextRun = now.Add(time.Duration(rand.Int63n(int64(minInterval))))
+		return false
+	}
+	if state.inFlight || now.Before(state.nextRun) {
+		return false
+	}
+	state.inFlight = true
+	return true
+}
+
+// release clears the in-flight flag without rescheduling
+func (s *Service) release(sessionID string) {
+	s.mu.Lock()
+	if state, ok := s.states[sessionID]; ok {
+		state.inFlight = false
+	}
+	s.mu.Unlock()
+}
+
+// reschedule computes the next run time for a session from the analysis outcome
+func (s *Service) reschedule(sessionID string, cfg Config, result analysisResult) {
+	base := time.Duration(cfg.Interval) * time.Second
+	if base < minInterval {
+		base = minInterval
+	}
+	maxIdle := time.Duration(cfg.MaxIdleInterval) * time.Second
+	if cfg.MaxIdleInterval <= 0 {
+		maxIdle = DefaultMaxIdleInterval * time.Second
+	}
+	if maxIdle < base {
+		maxIdle = base
+	}
+
+	now := time.Now()
+	switch result {
+	case resultChanged:
+		s.breaker.success()
+	case resultFailed:
+		s.breaker.failure(now)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[sessionID]
+	if !ok {
+		return // Session was cleaned up while running
+	}
+	state.inFlight = false
+
+	interval := base
+	switch result {
+	case resultChanged:
+		// Recent activity: return to the base interval
+		state.idleStreak = 0
+		state.failures = 0
+	case resultUnchanged:
+		// Idle: back off gradually up to maxIdle
+		state.idleStreak++
+		state.failures = 0
+		interval = time.Duration(float64(base) * math.Pow(idleBackoffFactor, float64(state.idleStreak)))
+		if interval > maxIdle || interval <= 0 {
+			interval = maxIdle
+		}
+		// Keep checking at the base rate while a notification is waiting for its delay
+		if len(state.pendingNotify) > 0 || state.rulesWaiting() {
+			interval = base
+		}
+	case resultFailed:
+		// Exponential backoff on consecutive failures
+		state.failures++
+		interval = base << uint(min(state.failures, 16))
+		if interval > maxFailureBackoff || interval <= 0 {
+			interval = maxFailureBackoff
+		}
+	}
+
+	state.nextRun = now.Add(jitter(interval))
+}
+
+// jitter spreads a duration by ±jitterFraction
+func jitter(d time.Duration) time.Duration {
+	return time.Duration(float64(d) * (1 - jitterFraction + 2*jitterFraction*rand.Float64()))
+}
@@ -5,17 +5,24 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"log"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"winterm-bridge/internal/actions"
 	"winterm-bridge/internal/config"
 	"winterm-bridge/internal/email"
+	"winterm-bridge/internal/events"
 	"winterm-bridge/internal/llm"
+	"winterm-bridge/internal/logging"
+	"winterm-bridge/internal/notify"
 	"winterm-bridge/internal/tmux"
 )
 
+var logger = logging.For("monitor")
+
 // SessionInfo contains the minimal info needed for monitoring
 type SessionInfo struct {
 	ID       string
@@ -30,6 +37,8 @@ type SessionProvider interface {
 	GetAllSessions() []SessionInfo
 	// BroadcastToSession sends a text message to all WebSocket subscribers of a session
 	BroadcastToSession(sessionID string, data []byte)
+	// ClientActivity returns the attached client count and the time of the last input
+	ClientActivity(sessionID string) (clients int, lastInput time.Time)
 }
 
 // SummaryMessage is the JSON message sent to frontend
@@ -43,36 +52,59 @@ type SummaryMessage struct {
 
 // sessionState tracks per-session monitoring state
 type sessionState struct {
-	lastHash     string
-	lastSummary  *llm.Summary
-	summaryTime  time.Time
+	lastHash    string
+	lastSummary *llm.Summary
+	summaryTime time.Time
+	// Scheduling
+	nextRun    time.Time // When the session is next due for capture
+	inFlight   bool      // Analysis currently running
+	idleStreak int       // Consecutive unchanged captures
+	failures   int       // Consecutive LLM failures
 	// Notification tracking
-	notifiedTags  map[string]bool      // Tags that have been notified (only notify once per tag)
-	pendingNotify map[string]time.Time // Tags pending notification (tag -> first detected time)
+	notifiedTags  map[string]bool       // Tags notified since the session entered them (cleared on leaving)
+	pendingNotify map[string]time.Time  // Tags pending notification (tag -> first detected time)
+	rules         map[string]*ruleState // Routing rule progress (rule name -> state)
+	// Client presence, from attach/detach events
+	clients    int
+	lastAttach time.Time
 }
 
 // Service is the AI monitoring service
 type Service struct {
-	provider     llm.Provider
-	sessions     SessionProvider
-	emailSender  *email.Sender
-	config       Config
-	states       map[string]*sessionState
-	mu           sync.RWMutex
-	cancel       context.CancelFunc
-	running      bool
-}
-
-// Config holds the monitor configuration
-type Config struct {
-	Enabled  bool   `json:"enabled"`
-	Endpoint string `json:"endpoint"`
-	APIKey   string `json:"api_key"`
-	Model    string `json:"model"`
-	Lines    int    `json:"lines"`
-	Interval int    `json:"interval"` // seconds
+	provider    llm.Provider
+	apiKey      string                  // Resolved from config.APIKey when started
+	providers   map[string]llm.Provider // Per-model providers for session overrides
+	sessions    SessionProvider
+	events      *events.Hub
+	emailSender *email.Sender
+	config      Config
+	states      map[string]*sessionState
+	history     map[string]*summaryHistory // Per-session summary timelines
+	historyInit bool                       // Whether persisted history has been loaded
+	stats       *Stats
+	breaker     circuitBreaker
+	redactor    *Redactor
+	actions     *actions.Manager // Issues reply links; nil disables them
+	paused      bool             // Paused because the daily budget was reached
+	now         func() time.Time // Clock used for notification timing
+	mu          sync.RWMutex
+	cancel      context.CancelFunc
+	loopDone    chan struct{} // Closed when the running loop and its workers have exited
+	running     bool
+	suspended   bool         // Handing over to a new process; Start does nothing until Resume
+	lastTick    atomic.Int64 // Unix nanoseconds of the last scheduler tick, for readiness checks
+
+	// Notification channels
+	emailNotifier  notify.Notifier
+	webPush        notify.Notifier   // Browser push, nil if keys are unavailable
+	channels       []notify.Notifier // Enabled non-email channels
+	channelConfigs []config.NotifyChannelConfig
+	rules          []config.NotifyRule
 }
 
+// Config holds the monitor configuration: the ai_monitor section of runtime.json
+type Config = config.AIMonitorConfig
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() Config {
 	return Config{
@@ -82,9 +114,17 @@ func DefaultConfig() Config {
 		Model:    "qwen-turbo",
 		Lines:    50,
 		Interval: 30,
+
+		HistorySize: DefaultHistorySize,
+
+		Concurrency:     DefaultConcurrency,
+		MaxIdleInterval: DefaultMaxIdleInterval,
 	}
 }
 
+// minInterval is the shortest allowed analysis interval
+const minInterval = 5 * time.Second
+
 // NewService creates a new monitor service
 func NewService(sessions SessionProvider) *Service {
 	s := &Service{
@@ -92,19 +132,54 @@ func NewService(sessions SessionProvider) *Service {
 		emailSender: email.NewSender(),
 		config:      DefaultConfig(),
 		states:      make(map[string]*sessionState),
+		history:     make(map[string]*summaryHistory),
+		stats:       NewStats(),
+		now:         time.Now,
 	}
+	s.redactor, _ = NewRedactor(nil)
+	s.emailNotifier = notify.NewEmailNotifier(s.emailSender)
 	// Load email config if available
 	if emailCfg := config.GetEmailConfig(); emailCfg != nil {
 		s.emailSender.UpdateConfig(emailCfg)
 	}
+	s.loadNotifyChannels()
+	s.loadWebPush()
+	s.loadNotifyRules()
+	config.Subscribe(s.applyFileConfig)
 	return s
 }
 
+// SetEventHub sets the hub that receives summary events for session-list subscribers
+// The monitor also listens on it for client attach/detach to drive notification escalation,
+// and for deleted sessions to drop their state and history
+func (s *Service) SetEventHub(hub *events.Hub) {
+	s.mu.Lock()
+	s.events = hub
+	s.mu.Unlock()
+	if hub != nil {
+		go s.watchEvents(hub)
+	}
+}
+
+// SetActionManager enables one-click reply actions on notifications for sessions waiting on input
+func (s *Service) SetActionManager(m *actions.Manager) {
+	s.mu.Lock()
+	s.actions = m
+	s.mu.Unlock()
+}
+
 // UpdateConfig updates the monitor configuration and restarts if needed
 func (s *Service) UpdateConfig(cfg Config) {
 	s.mu.Lock()
 	wasRunning := s.running
 	s.config = cfg
+	s.applyHistoryConfigLocked(cfg)
+	if redactor, err := NewRedactor(cfg.RedactPatterns); err != nil {
+		logger.Warn("Invalid redact patterns, using built-in redaction only", "err", err)
+		s.redactor, _ = NewRedactor(nil)
+	} else {
+		s.redactor = redactor
+	}
 	s.mu.Unlock()
 
 	// Restart if config changed and was running
@@ -129,9 +204,9 @@ func (s *Service) GetEmailConfig() *config.EmailConfig {
 	return s.emailSender.GetConfig()
 }
 
-// UpdateEmailConfig updates the email configuration
-func (s *Service) UpdateEmailConfig(cfg *config.EmailConfig) {
-	s.emailSender.UpdateConfig(cfg)
+// GetEmailOutbox returns the emails waiting for delivery
+func (s *Service) GetEmailOutbox() []email.OutboxStatus {
+	return s.emailSender.Outbox().Status()
 }
 
 // TestEmail sends a test email
@@ -158,6 +233,95 @@ func (s *Service) GetSummary(sessionID string) *SummaryMessage {
 	}
 }
 
+// GetStats returns LLM usage statistics
+func (s *Service) GetStats() StatsSnapshot {
+	s.mu.RLock()
+	budget := s.config.DailyBudget
+	s.mu.RUnlock()
+	return s.stats.Snapshot(budget)
+}
+
+// GetHistory returns the summary transitions for a session, oldest first
+func (s *Service) GetHistory(sessionID string) []HistoryEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h, ok := s.history[sessionID]
+	if !ok {
+		return []HistoryEntry{}
+	}
+	return h.list()
+}
+
+// applyHistoryConfigLocked loads persisted history and applies the history size
+// Caller must hold s.mu
+func (s *Service) applyHistoryConfigLocked(cfg Config) {
+	if cfg.PersistHistory && !s.historyInit {
+		for sessionID, h := range loadHistory(cfg.HistorySize) {
+			if _, exists := s.history[sessionID]; !exists {
+				s.history[sessionID] = h
+			}
+		}
+		s.historyInit = true
+	}
+
+	size := cfg.HistorySize
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	for sessionID, h := range s.history {
+		if len(h.entries) != size {
+			s.history[sessionID] = h.resize(size)
+		}
+	}
+}
+
+// recordHistory appends a summary transition to a session's timeline
+func (s *Service) recordHistory(sessionID, hash string, summary *llm.Summary, at time.Time) {
+	s.mu.Lock()
+	h, ok := s.history[sessionID]
+	if !ok {
+		h = newSummaryHistory(s.config.HistorySize)
+		s.history[sessionID] = h
+	}
+	added := h.add(HistoryEntry{
+		Timestamp:   at.Unix(),
+		Tag:         summary.Tag,
+		Description: summary.Description,
+		Hash:        hash,
+	})
+	var snapshot map[string][]HistoryEntry
+	if added {
+		snapshot = s.historySnapshotLocked()
+	}
+	s.mu.Unlock()
+
+	s.saveHistorySnapshot(snapshot)
+}
+
+// historySnapshotLocked copies the history for saving, or returns nil if it isn't persisted
+// Caller must hold s.mu
+func (s *Service) historySnapshotLocked() map[string][]HistoryEntry {
+	if !s.config.PersistHistory {
+		return nil
+	}
+	snapshot := make(map[string][]HistoryEntry, len(s.history))
+	for id, sh := range s.history {
+		snapshot[id] = sh.list()
+	}
+	return snapshot
+}
+
+// saveHistorySnapshot writes a snapshot from historySnapshotLocked; nil is a no-op
+func (s *Service) saveHistorySnapshot(snapshot map[string][]HistoryEntry) {
+	if snapshot == nil {
+		return
+	}
+	if err := saveHistory(snapshot); err != nil {
+		logger.Error("Failed to save summary history", "err", err)
+	}
+}
+
 // Start begins the monitoring loop
 func (s *Service) Start() {
 	s.mu.Lock()
@@ -167,26 +331,40 @@ func (s *Service) Start() {
 	}
 
 	cfg := s.config
-	if !cfg.Enabled || cfg.APIKey == "" {
+	if !cfg.Enabled || cfg.APIKey == "" || s.suspended {
 		s.mu.Unlock()
 		return
 	}
 
 	// Create LLM provider
+	apiKey, err := config.ResolveSecret(cfg.APIKey)
+	if err != nil {
+		s.mu.Unlock()
+		logger.Error("AI monitor not started, can't resolve api_key", "err", err)
+		return
+	}
+	s.apiKey = apiKey
 	s.provider = llm.NewOpenAICompatProvider(llm.Config{
 		Endpoint: cfg.Endpoint,
-		APIKey:   cfg.APIKey,
+		APIKey:   apiKey,
 		Model:    cfg.Model,
 	})
+	s.providers = make(map[string]llm.Provider)
 
 	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
 	s.cancel = cancel
+	s.loopDone = done
 	s.running = true
+	s.lastTick.Store(time.Now().UnixNano())
 	s.mu.Unlock()
 
-	log.Printf("[Monitor] AI monitor started (interval: %ds, lines: %d)", cfg.Interval, cfg.Lines)
+	logger.Info("AI monitor started", "interval_s", cfg.Interval, "lines", cfg.Lines)
 
-	go s.loop(ctx)
+	go func() {
+		s.loop(ctx)
+		close(done)
+	}()
 }
 
 // Stop stops the monitoring loop
@@ -202,7 +380,93 @@ func (s *Service) Stop() {
 		s.cancel()
 	}
 	s.running = false
-	log.Printf("[Monitor] AI monitor stopped")
+	logger.Info("AI monitor stopped")
+}
+
+// Shutdown stops analysis, waits for in-flight LLM calls and tries to deliver
+// queued emails, giving up when ctx expires (undelivered emails stay in the outbox file)
+func (s *Service) Shutdown(ctx context.Context) {
+	s.mu.RLock()
+	done := s.loopDone
+	s.mu.RUnlock()
+
+	s.Stop()
+	s.waitLoop(ctx, done)
+
+	flushed := make(chan int, 1)
+	go func() {
+		outbox := s.emailSender.Outbox()
+		outbox.Stop()
+		flushed <- outbox.Flush()
+	}()
+	select {
+	case remaining := <-flushed:
+		if remaining > 0 {
+			logger.Info("Emails left in outbox for next start", "count", remaining)
+		}
+	case <-ctx.Done():
+		logger.Warn("Timed out flushing email outbox")
+	}
+}
+
+// Suspend stops analysis and the email worker without flushing and keeps them stopped,
+// even across config changes, until Resume. A restart calls it before starting the new
+// process so the two never deliver the same notifications or write the outbox at once
+func (s *Service) Suspend(ctx context.Context) {
+	s.mu.Lock()
+	s.suspended = true
+	done := s.loopDone
+	s.mu.Unlock()
+
+	s.Stop()
+	s.waitLoop(ctx, done)
+	s.emailSender.Outbox().Stop()
+}
+
+// Resume restarts what Suspend stopped, after a restart failed
+func (s *Service) Resume() {
+	s.mu.Lock()
+	s.suspended = false
+	s.mu.Unlock()
+
+	s.emailSender.Outbox().Start()
+	s.Start()
+}
+
+// waitLoop waits for a stopped loop's in-flight analyses to finish
+func (s *Service) waitLoop(ctx context.Context, done <-chan struct{}) {
+	if done == nil {
+		return
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Warn("Timed out waiting for in-flight analysis")
+	}
+}
+
+// applyFileConfig applies changed AI monitor, email, channel, web push and rule settings
+// Registered with config.Subscribe, so it sees API saves and edits to runtime.json alike
+func (s *Service) applyFileConfig(old, cfg *config.Config) {
+	if !reflect.DeepEqual(old.AIMonitor, cfg.AIMonitor) {
+		aiCfg := DefaultConfig()
+		if cfg.AIMonitor != nil {
+			aiCfg = *cfg.AIMonitor
+		}
+		s.UpdateConfig(aiCfg)
+	}
+	if !reflect.DeepEqual(old.Email, cfg.Email) {
+		s.emailSender.UpdateConfig(cfg.Email)
+	}
+	if !reflect.DeepEqual(old.NotifyChannels, cfg.NotifyChannels) {
+		s.loadNotifyChannels()
+	}
+	if !reflect.DeepEqual(old.WebPush, cfg.WebPush) {
+		s.loadWebPush()
+	}
+	if !reflect.DeepEqual(old.NotifyRules, cfg.NotifyRules) {
+		s.loadNotifyRules()
+	}
 }
 
 // IsRunning returns whether the monitor is active
@@ -212,108 +476,166 @@ func (s *Service) IsRunning() bool {
 	return s.running
 }
 
-// loop is the main monitoring loop
-func (s *Service) loop(ctx context.Context) {
+// LastTick returns when the scheduler loop last ticked (or was started)
+func (s *Service) LastTick() time.Time {
+	if n := s.lastTick.Load(); n != 0 {
+		return time.Unix(0, n)
+	}
+	return time.Time{}
+}
+
+// checkBudget returns true if analysis is paused because the daily budget was reached
+func (s *Service) checkBudget() bool {
 	s.mu.RLock()
-	interval := time.Duration(s.config.Interval) * time.Second
+	budget := s.config.DailyBudget
 	s.mu.RUnlock()
 
-	if interval < 5*time.Second {
-		interval = 5 * time.Second
+	exceeded := s.stats.BudgetExceeded(budget)
+
+	s.mu.Lock()
+	if exceeded != s.paused {
+		s.paused = exceeded
+		if exceeded {
+			logger.Warn("Daily budget reached, analysis paused until tomorrow", "budget", budget)
+		} else {
+			logger.Info("Daily budget reset, analysis resumed")
+		}
 	}
+	s.mu.Unlock()
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	return exceeded
+}
+
+// IsPaused returns whether analysis is paused by the daily budget cap
+func (s *Service) IsPaused() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.paused
+}
+
+// sessionConfig returns the monitor configuration with per-session overrides applied
+func (s *Service) sessionConfig(sessionID string) Config {
+	s.mu.RLock()
+	cfg := s.config
+	s.mu.RUnlock()
+
+	override := config.GetSessionMonitorSettings(sessionID)
+	if override == nil {
+		return cfg
+	}
+	if override.Disabled || override.NeverSend {
+		cfg.Enabled = false
+	}
+	if override.Lines > 0 {
+		cfg.Lines = override.Lines
+	}
+	if override.Interval > 0 {
+		cfg.Interval = override.Interval
+	}
+	if override.Model != "" {
+		cfg.Model = override.Model
+	}
+	return cfg
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			s.analyzeAllSessions(ctx)
+// getOrCreateStateLocked returns the state for a session, creating it if needed
+// Caller must hold s.mu
+func (s *Service) getOrCreateStateLocked(sessionID string) *sessionState {
+	state, ok := s.states[sessionID]
+	if !ok {
+		state = &sessionState{
+			notifiedTags:  make(map[string]bool),
+			pendingNotify: make(map[string]time.Time),
 		}
+		s.states[sessionID] = state
 	}
+	return state
 }
 
-// analyzeAllSessions checks and analyzes all sessions
-func (s *Service) analyzeAllSessions(ctx context.Context) {
-	sessions := s.sessions.GetAllSessions()
+// providerFor returns the LLM provider for a model, reusing the default provider when possible
+func (s *Service) providerFor(model string) llm.Provider {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	for _, sess := range sessions {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			// Skip ghost sessions (no tmux to capture)
-			if sess.IsGhost {
-				continue
-			}
-			s.analyzeSession(ctx, sess)
-		}
+	if model == "" || model == s.config.Model {
+		return s.provider
 	}
+	if p, ok := s.providers[model]; ok {
+		return p
+	}
+	p := llm.NewOpenAICompatProvider(llm.Config{
+		Endpoint: s.config.Endpoint,
+		APIKey:   s.apiKey,
+		Model:    model,
+	})
+	s.providers[model] = p
+	return p
 }
 
 // analyzeSession checks a single session for changes and triggers analysis
-func (s *Service) analyzeSession(ctx context.Context, sess SessionInfo) {
-	s.mu.RLock()
-	lines := s.config.Lines
-	s.mu.RUnlock()
+func (s *Service) analyzeSession(ctx context.Context, sess SessionInfo, cfg Config) analysisResult {
+	lines := cfg.Lines
 
 	// Capture terminal content directly from tmux
 	content, err := tmux.CaptureSessionPane(sess.TmuxName, lines)
 	if err != nil {
 		// Session might not exist or is detached, skip silently
-		return
+		return resultSkipped
 	}
 
 	if content == "" {
-		return
+		return resultSkipped
 	}
 
 	// Normalize content for hash comparison (filter empty lines)
 	normalizedContent := normalizeContent(content)
 	if normalizedContent == "" {
-		return
+		return resultSkipped
 	}
 
 	// Calculate content hash for change detection
 	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(normalizedContent)))
 
-	s.mu.RLock()
-	state, exists := s.states[sess.ID]
-	s.mu.RUnlock()
+	s.mu.Lock()
+	state := s.getOrCreateStateLocked(sess.ID)
+	lastHash, lastSummary := state.lastHash, state.lastSummary
+	s.mu.Unlock()
 
 	// Skip if content hasn't changed
-	if exists && state.lastHash == hash {
+	if lastHash == hash {
 		// Content unchanged, but still check pending notifications
-		if state.lastSummary != nil {
-			s.checkAndSendNotification(sess, state.lastSummary, state)
+		if lastSummary != nil {
+			s.checkAndSendNotification(sess, lastSummary, state)
 		}
-		return
+		return resultUnchanged
 	}
 
+	// Strip secrets before content leaves the machine
+	redacted, _ := s.redact(content)
+
 	// Call LLM
-	summary, err := s.provider.Summarize(ctx, content)
+	start := time.Now()
+	summary, err := s.providerFor(cfg.Model).Summarize(ctx, redacted)
+	var usage llm.Usage
+	if summary != nil {
+		usage = summary.Usage
+	}
+	s.stats.Record(cfg.Model+"@"+cfg.Endpoint, sess.ID, usage, cfg.Prices[cfg.Model], time.Since(start), err)
 	if err != nil {
-		log.Printf("[Monitor] Failed to analyze session %s: %v", sess.ID[:8], err)
-		return
+		logger.Warn("Failed to analyze session", "session", sess.ID[:8], "err", err)
+		return resultFailed
 	}
 
 	// Update state
 	s.mu.Lock()
-	if !exists {
-		state = &sessionState{
-			notifiedTags:  make(map[string]bool),
-			pendingNotify: make(map[string]time.Time),
-		}
-		s.states[sess.ID] = state
-	}
-
+	now := time.Now()
 	state.lastHash = hash
 	state.lastSummary = summary
-	state.summaryTime = time.Now()
+	state.summaryTime = now
 	s.mu.Unlock()
 
+	s.recordHistory(sess.ID, hash, summary, now)
+
 	// Check if we should send notification
 	s.checkAndSendNotification(sess, summary, state)
 
@@ -328,17 +650,30 @@ func (s *Service) analyzeSession(ctx context.Context, sess SessionInfo) {
 
 	msgData, err := json.Marshal(msg)
 	if err != nil {
-		return
+		return resultChanged
 	}
 
 	s.sessions.BroadcastToSession(sess.ID, msgData)
+
+	s.mu.RLock()
+	hub := s.events
+	s.mu.RUnlock()
+	hub.Publish(events.TypeAISummary, sess.ID, msg)
+	return resultChanged
 }
 
-// CleanupSession removes monitoring state for a session
+// CleanupSession removes monitoring state for a session, including its persisted history
 func (s *Service) CleanupSession(sessionID string) {
 	s.mu.Lock()
 	delete(s.states, sessionID)
+	var snapshot map[string][]HistoryEntry
+	if _, ok := s.history[sessionID]; ok {
+		delete(s.history, sessionID)
+		snapshot = s.historySnapshotLocked()
+	}
 	s.mu.Unlock()
+	s.saveHistorySnapshot(snapshot)
+	s.stats.RemoveSession(sessionID)
 }
 
 // Tags that should trigger notifications
@@ -349,10 +684,19 @@ var notifiableTags = map[string]bool{
 	"错误":  true,
 }
 
+// notifyTimeout bounds the fan-out to all notification channels
+const notifyTimeout = 30 * time.Second
+
+// typingWindow is how recent client input must be to count as actively typing
+const typingWindow = 60 * time.Second
+
 // checkAndSendNotification checks if we should send a notification for this session
+// A notification fires once when the session enters a notifiable tag and the tag has
+// persisted for the notify delay; leaving the tag re-arms it
 func (s *Service) checkAndSendNotification(sess SessionInfo, summary *llm.Summary, state *sessionState) {
 	// Check if this tag should trigger notification
 	isNotifiable := notifiableTags[summary.Tag]
+	now := s.now()
 
 	s.mu.Lock()
 	// Initialize maps if nil
@@ -363,14 +707,33 @@ func (s *Service) checkAndSendNotification(sess SessionInfo, summary *llm.Summar
 		state.notifiedTags = make(map[string]bool)
 	}
 
-	// Clear pending notifications for tags that are no longer active
+	// Leaving a tag clears its pending timer and re-arms its notification
 	for tag := range state.pendingNotify {
 		if tag != summary.Tag {
 			delete(state.pendingNotify, tag)
 		}
 	}
+	for tag := range state.notifiedTags {
+		if tag != summary.Tag {
+			delete(state.notifiedTags, tag)
+		}
+	}
 	s.mu.Unlock()
 
+	// The user is at the terminal; don't notify them about what they're looking at
+	typing := s.isTyping(sess.ID, now)
+
+	// Routing rules replace the default behavior when configured
+	s.mu.RLock()
+	rules := s.rules
+	s.mu.RUnlock()
+	if len(rules) > 0 {
+		if config.GetSessionNotifyEnabled(sess.ID) {
+			s.evaluateRules(sess, summary, state, rules, now, typing)
+		}
+		return
+	}
+
 	// If not a notifiable tag, nothing more to do
 	if !isNotifiable {
 		return
@@ -381,12 +744,13 @@ func (s *Service) checkAndSendNotification(sess SessionInfo, summary *llm.Summar
 		return
 	}
 
-	// Check if email is configured
-	if !s.emailSender.IsEnabled() {
+	// Check if any notification channel is configured
+	notifiers := s.activeNotifiers()
+	if len(notifiers) == 0 {
 		return
 	}
 
-	// Check if this tag has already been notified (only notify once per tag)
+	// Check if this tag has already been notified since the session entered it
 	s.mu.RLock()
 	alreadyNotified := state.notifiedTags[summary.Tag]
 	pendingTime, isPending := state.pendingNotify[summary.Tag]
@@ -396,17 +760,8 @@ func (s *Service) checkAndSendNotification(sess SessionInfo, summary *llm.Summar
 		return
 	}
 
-	// Get notify delay from email config
-	emailCfg := s.emailSender.GetConfig()
-	notifyDelay := 60 // default 60 seconds
-	if emailCfg != nil && emailCfg.NotifyDelay > 0 {
-		notifyDelay = emailCfg.NotifyDelay
-	}
-
-	now := time.Now()
-
-	// If not pending, start the pending timer
-	if !isPending {
+	// If not pending, or the user is typing, (re)start the pending timer
+	if !isPending || typing {
 		s.mu.Lock()
 		state.pendingNotify[summary.Tag] = now
 		s.mu.Unlock()
@@ -414,22 +769,27 @@ func (s *Service) checkAndSendNotification(sess SessionInfo, summary *llm.Summar
 	}
 
 	// Check if delay has passed
-	if now.Sub(pendingTime) < time.Duration(notifyDelay)*time.Second {
+	if now.Sub(pendingTime) < s.notifyDelay() {
 		// Delay not yet passed, wait for next check
 		return
 	}
 
 	// Delay has passed, send notification
-	sessionTitle := sess.Title
-	if sessionTitle == "" {
-		sessionTitle = sess.TmuxName
-	}
-	if sessionTitle == "" {
-		sessionTitle = sess.ID[:8]
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
 
-	if err := s.emailSender.SendNotification(sessionTitle, sess.ID, summary.Tag, summary.Description); err != nil {
-		log.Printf("[Monitor] Failed to send notification for session %s: %v", sess.ID[:8], err)
+	results := notify.SendAll(ctx, notifiers, s.buildNotification(sess, summary, now))
+
+	// Consider the notification delivered if any channel succeeded
+	delivered := false
+	for _, r := range results {
+		if r.Error != "" {
+			logger.Warn("Failed to send notification", "channel", r.Channel, "session", sess.ID[:8], "err", r.Error)
+			continue
+		}
+		delivered = true
+	}
+	if !delivered {
 		return
 	}
 
@@ -440,11 +800,136 @@ func (s *Service) checkAndSendNotification(sess SessionInfo, summary *llm.Summar
 	s.mu.Unlock()
 }
 
+// buildNotification assembles a notification, attaching a redacted terminal excerpt for email
+// and reply actions when the session is waiting on input
+func (s *Service) buildNotification(sess SessionInfo, summary *llm.Summary, now time.Time) notify.Notification {
+	n := notify.Notification{
+		SessionID:    sess.ID,
+		SessionTitle: sessionTitle(sess),
+		Tag:          summary.Tag,
+		Description:  summary.Description,
+		Time:         now,
+	}
+	if lines := s.emailSender.ExcerptLines(); lines > 0 && s.emailSender.IsEnabled() {
+		if content, err := tmux.CaptureSessionPaneANSI(sess.TmuxName, lines); err == nil {
+			n.Excerpt, _ = s.redact(content)
+		}
+	}
+	s.mu.RLock()
+	am := s.actions
+	s.mu.RUnlock()
+	if am != nil {
+		n.Actions = am.ForTag(sess.ID, summary.Tag, s.emailSender.GetConfig().PublicURL)
+	}
+	return n
+}
+
+// isTyping reports whether a client is attached and has sent input recently
+func (s *Service) isTyping(sessionID string, now time.Time) bool {
+	clients, lastInput := s.sessions.ClientActivity(sessionID)
+	return clients > 0 && now.Sub(lastInput) < typingWindow
+}
+
+// notifyDelay returns how long a tag must persist before notifying
+// Configured on the email settings but applies to all channels
+func (s *Service) notifyDelay() time.Duration {
+	emailCfg := s.emailSender.GetConfig()
+	notifyDelay := 60 // default 60 seconds
+	if emailCfg != nil && emailCfg.NotifyDelay > 0 {
+		notifyDelay = emailCfg.NotifyDelay
+	}
+	return time.Duration(notifyDelay) * time.Second
+}
+
+// sessionTitle returns the display name used in notifications
+func sessionTitle(sess SessionInfo) string {
+	if sess.Title != "" {
+		return sess.Title
+	}
+	if sess.TmuxName != "" {
+		return sess.TmuxName
+	}
+	return sess.ID[:8]
+}
+
+// redact applies the configured redaction rules to terminal content
+func (s *Service) redact(content string) (string, []RedactionCount) {
+	s.mu.RLock()
+	redactor := s.redactor
+	s.mu.RUnlock()
+	return redactor.Redact(content)
+}
+
+// PreviewResult shows what would be sent to the LLM for a session
+type PreviewResult struct {
+	SessionID  string           `json:"session_id"`
+	WouldSend  bool             `json:"would_send"`
+	Reason     string           `json:"reason,omitempty"` // Why content would not be sent
+	Model      string           `json:"model"`
+	Content    string           `json:"content"`
+	Redactions []RedactionCount `json:"redactions"`
+}
+
+// Preview captures a session and returns the redacted content without calling the LLM
+func (s *Service) Preview(sessionID string) (*PreviewResult, error) {
+	var sess *SessionInfo
+	for _, info := range s.sessions.GetAllSessions() {
+		if info.ID == sessionID {
+			sess = &info
+			break
+		}
+	}
+	if sess == nil {
+		return nil, fmt.Errorf("session not found")
+	}
+
+	cfg := s.sessionConfig(sessionID)
+	result := &PreviewResult{
+		SessionID:  sessionID,
+		WouldSend:  true,
+		Model:      cfg.Model,
+		Redactions: []RedactionCount{},
+	}
+
+	if override := config.GetSessionMonitorSettings(sessionID); override != nil && override.NeverSend {
+		// Don't even capture content for sessions opted out of AI entirely
+		result.WouldSend = false
+		result.Reason = "session is marked never send to AI"
+		return result, nil
+	}
+
+	switch {
+	case sess.IsGhost:
+		result.WouldSend = false
+		result.Reason = "session is a ghost (no tmux)"
+		return result, nil
+	case !cfg.Enabled:
+		result.WouldSend = false
+		result.Reason = "AI analysis is disabled"
+	}
+
+	content, err := tmux.CaptureSessionPane(sess.TmuxName, cfg.Lines)
+	if err != nil {
+		return nil, err
+	}
+
+	redacted, counts := s.redact(content)
+	result.Content = redacted
+	if counts != nil {
+		result.Redactions = counts
+	}
+	return result, nil
+}
+
 // TestConnection tests the LLM API connection
 func (s *Service) TestConnection(ctx context.Context, cfg Config) error {
+	apiKey, err := config.ResolveSecret(cfg.APIKey)
+	if err != nil {
+		return err
+	}
 	provider := llm.NewOpenAICompatProvider(llm.Config{
 		Endpoint: cfg.Endpoint,
-		APIKey:   cfg.APIKey,
+		APIKey:   apiKey,
 		Model:    cfg.Model,
 	})
 	return provider.TestConnection(ctx)
@@ -0,0 +1,160 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/email"
+	"winterm-bridge/internal/llm"
+	"winterm-bridge/internal/notify"
+)
+
+// fakeNotifier records the notifications it is asked to send
+type fakeNotifier struct {
+	name string
+	mu   sync.Mutex
+	sent []notify.Notification
+}
+
+func (f *fakeNotifier) Name() string { return f.name }
+
+func (f *fakeNotifier) Send(ctx context.Context, n notify.Notification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, n)
+	return nil
+}
+
+func (f *fakeNotifier) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sent)
+}
+
+// fakeSessions reports client activity set by the test
+type fakeSessions struct {
+	clients   int
+	lastInput time.Time
+}
+
+func (f *fakeSessions) GetAllSessions() []SessionInfo                    { return nil }
+func (f *fakeSessions) BroadcastToSession(sessionID string, data []byte) {}
+func (f *fakeSessions) ClientActivity(sessionID string) (int, time.Time) {
+	return f.clients, f.lastInput
+}
+
+// notifyHarness drives checkAndSendNotification for one session with a fake clock
+type notifyHarness struct {
+	t        *testing.T
+	svc      *Service
+	sessions *fakeSessions
+	notifier *fakeNotifier
+	sess     SessionInfo
+	state    *sessionState
+	now      time.Time
+}
+
+func newNotifyHarness(t *testing.T, sessionID string) *notifyHarness {
+	t.Setenv("HOME", t.TempDir())
+	if err := config.SetSessionNotifyEnabled(sessionID, true); err != nil {
+		t.Fatalf("enabling notifications: %v", err)
+	}
+
+	h := &notifyHarness{
+		t:        t,
+		sessions: &fakeSessions{},
+		notifier: &fakeNotifier{name: "fake"},
+		sess:     SessionInfo{ID: sessionID, Title: "test"},
+		state:    &sessionState{},
+		now:      time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	h.svc = &Service{
+		sessions:    h.sessions,
+		emailSender: &email.Sender{},
+		config:      DefaultConfig(),
+		states:      make(map[string]*sessionState),
+		channels:    []notify.Notifier{h.notifier},
+		now:         func() time.Time { return h.now },
+	}
+	return h
+}
+
+// check advances the clock by d and reports tag for the session
+func (h *notifyHarness) check(d time.Duration, tag string) {
+	h.now = h.now.Add(d)
+	h.svc.checkAndSendNotification(h.sess, &llm.Summary{Tag: tag, Description: "desc"}, h.state)
+}
+
+func (h *notifyHarness) expectSent(want int) {
+	h.t.Helper()
+	if got := h.notifier.count(); got != want {
+		h.t.Fatalf("sent %d notifications, want %d", got, want)
+	}
+}
+
+// The default notify delay, since the fake email sender has no config
+const testDelay = 60 * time.Second
+
+func TestNotifyOnceOnEnteringTag(t *testing.T) {
+	h := newNotifyHarness(t, "11111111-enter")
+
+	h.check(0, "进行")
+	h.check(10*time.Second, "完毕")
+	h.expectSent(0)
+
+	h.check(testDelay/2, "完毕")
+	h.expectSent(0)
+
+	h.check(testDelay/2, "完毕")
+	h.expectSent(1)
+	if got := h.notifier.sent[0].Tag; got != "完毕" {
+		t.Fatalf("notified tag %q, want 完毕", got)
+	}
+
+	// Staying in the tag doesn't notify again
+	for i := 0; i < 5; i++ {
+		h.check(testDelay, "完毕")
+	}
+	h.expectSent(1)
+}
+
+func TestNotifyRearmsAfterLeavingTag(t *testing.T) {
+	h := newNotifyHarness(t, "22222222-rearm")
+
+	h.check(0, "完毕")
+	h.check(testDelay, "完毕")
+	h.expectSent(1)
+
+	// Leaving clears the notified tag, so the next run's completion notifies again
+	h.check(10*time.Second, "进行")
+	h.check(10*time.Second, "完毕")
+	h.expectSent(1)
+	h.check(testDelay, "完毕")
+	h.expectSent(2)
+
+	// A brief visit to the tag that ends before the delay doesn't notify
+	h.check(10*time.Second, "进行")
+	h.check(10*time.Second, "错误")
+	h.check(10*time.Second, "进行")
+	h.check(testDelay, "进行")
+	h.expectSent(2)
+}
+
+func TestNotifySuppressedWhileTyping(t *testing.T) {
+	h := newNotifyHarness(t, "33333333-typing")
+	h.sessions.clients = 1
+
+	// Input within the typing window keeps restarting the delay
+	for i := 0; i < 4; i++ {
+		h.check(typingWindow/2, "需输入")
+		h.sessions.lastInput = h.now
+	}
+	h.expectSent(0)
+
+	// Once the user stops typing, the tag must still persist for the delay
+	h.check(typingWindow, "需输入")
+	h.expectSent(1)
+}
@@ -0,0 +1,257 @@
+package monitor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/llm"
+	"winterm-bridge/internal/metrics"
+)
+
+// latencyWindow is the number of recent latencies kept for percentile estimates
+const latencyWindow = 500
+
+// UsageCounters holds request, token, error and cost totals
+type UsageCounters struct {
+	Requests         int64   `json:"requests"`
+	Errors           int64   `json:"errors"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`           // Estimated from configured prices
+	LatencyP50Ms     int64   `json:"latency_p50_ms"` // Over the last latencyWindow requests
+	LatencyP95Ms     int64   `json:"latency_p95_ms"`
+}
+
+// DailyUsage holds today's totals used for the budget cap
+type DailyUsage struct {
+	Date             string  `json:"date"` // YYYY-MM-DD, local time
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// StatsSnapshot is a point-in-time copy of LLM usage statistics
+type StatsSnapshot struct {
+	Since          int64                    `json:"since"`
+	Total          UsageCounters            `json:"total"`
+	Providers      map[string]UsageCounters `json:"providers"` // Keyed by "model@endpoint"
+	Sessions       map[string]UsageCounters `json:"sessions"`  // Keyed by session ID
+	Today          DailyUsage               `json:"today"`
+	DailyBudget    float64                  `json:"daily_budget"`
+	BudgetExceeded bool                     `json:"budget_exceeded"`
+}
+
+// usageBucket accumulates counters and a latency window
+type usageBucket struct {
+	counters  UsageCounters
+	latencies []time.Duration // Ring of recent latencies
+	next      int
+}
+
+func (b *usageBucket) record(usage llm.Usage, cost float64, latency time.Duration, failed bool) {
+	b.counters.Requests++
+	if failed {
+		b.counters.Errors++
+	}
+	b.counters.PromptTokens += int64(usage.PromptTokens)
+	b.counters.CompletionTokens += int64(usage.CompletionTokens)
+	b.counters.Cost += cost
+
+	if len(b.latencies) < latencyWindow {
+		b.latencies = append(b.latencies, latency)
+	} else {
+		b.latencies[b.next] = latency
+		b.next = (b.next + 1) % latencyWindow
+	}
+}
+
+func (b *usageBucket) snapshot() UsageCounters {
+	c := b.counters
+	if len(b.latencies) > 0 {
+		sorted := make([]time.Duration, len(b.latencies))
+		copy(sorted, b.latencies)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		c.LatencyP50Ms = percentile(sorted, 50).Milliseconds()
+		c.LatencyP95Ms = percentile(sorted, 95).Milliseconds()
+	}
+	return c
+}
+
+// percentile returns the p-th percentile of sorted durations (nearest rank)
+func percentile(sorted []time.Duration, p int) time.Duration {
+	idx := (len(sorted)*p+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+// Stats tracks LLM request counts, tokens, latency and estimated cost
+type Stats struct {
+	since     time.Time
+	total     usageBucket
+	providers map[string]*usageBucket
+	sessions  map[string]*usageBucket
+	today     DailyUsage
+	mu        sync.Mutex
+}
+
+// NewStats creates an empty stats tracker
+// Today's totals are loaded from disk, so the daily budget holds across restarts
+func NewStats() *Stats {
+	return &Stats{
+		since:     time.Now(),
+		providers: make(map[string]*usageBucket),
+		sessions:  make(map[string]*usageBucket),
+		today:     loadDailyUsage(),
+	}
+}
+
+// Record adds the outcome of one LLM request
+func (st *Stats) Record(provider, sessionID string, usage llm.Usage, price config.ModelPrice, latency time.Duration, err error) {
+	cost := float64(usage.PromptTokens)/1000*price.PromptPer1K +
+		float64(usage.CompletionTokens)/1000*price.CompletionPer1K
+	failed := err != nil
+
+	metrics.LLMRequestDuration.Observe(latency.Seconds())
+	if failed {
+		metrics.LLMErrors.Inc()
+	}
+
+	st.mu.Lock()
+	st.total.record(usage, cost, latency, failed)
+
+	pb, ok := st.providers[provider]
+	if !ok {
+		pb = &usageBucket{}
+		st.providers[provider] = pb
+	}
+	pb.record(usage, cost, latency, failed)
+
+	if sessionID != "" {
+		sb, ok := st.sessions[sessionID]
+		if !ok {
+			sb = &usageBucket{}
+			st.sessions[sessionID] = sb
+		}
+		sb.record(usage, cost, latency, failed)
+	}
+
+	st.rollDayLocked(time.Now())
+	st.today.Requests++
+	st.today.PromptTokens += int64(usage.PromptTokens)
+	st.today.CompletionTokens += int64(usage.CompletionTokens)
+	st.today.Cost += cost
+	st.mu.Unlock()
+
+	if err := st.saveToday(); err != nil {
+		logger.Error("Failed to save daily usage", "err", err)
+	}
+}
+
+// BudgetExceeded reports whether today's estimated cost has reached the daily budget
+func (st *Stats) BudgetExceeded(budget float64) bool {
+	if budget <= 0 {
+		return false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.rollDayLocked(time.Now())
+	return st.today.Cost >= budget
+}
+
+// RemoveSession drops per-session counters
+func (st *Stats) RemoveSession(sessionID string) {
+	st.mu.Lock()
+	delete(st.sessions, sessionID)
+	st.mu.Unlock()
+}
+
+// Snapshot returns a copy of the current statistics
+func (st *Stats) Snapshot(budget float64) StatsSnapshot {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.rollDayLocked(time.Now())
+
+	snap := StatsSnapshot{
+		Since:          st.since.Unix(),
+		Total:          st.total.snapshot(),
+		Providers:      make(map[string]UsageCounters, len(st.providers)),
+		Sessions:       make(map[string]UsageCounters, len(st.sessions)),
+		Today:          st.today,
+		DailyBudget:    budget,
+		BudgetExceeded: budget > 0 && st.today.Cost >= budget,
+	}
+	for k, b := range st.providers {
+		snap.Providers[k] = b.snapshot()
+	}
+	for k, b := range st.sessions {
+		snap.Sessions[k] = b.snapshot()
+	}
+	return snap
+}
+
+// rollDayLocked resets the daily totals when the local date changes
+// Caller must hold st.mu
+func (st *Stats) rollDayLocked(now time.Time) {
+	date := now.Format("2006-01-02")
+	if st.today.Date != date {
+		st.today = DailyUsage{Date: date}
+	}
+}
+
+// dailyUsageFileMu serializes writes to the daily usage file
+var dailyUsageFileMu sync.Mutex
+
+// dailyUsagePath returns the path of the persisted daily usage file
+func dailyUsagePath() string {
+	return filepath.Join(config.DefaultConfigDir(), "daily_usage.json")
+}
+
+// loadDailyUsage reads the persisted daily totals; a previous day's are reset on first use
+func loadDailyUsage() DailyUsage {
+	var today DailyUsage
+	data, err := os.ReadFile(dailyUsagePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error("Failed to read daily usage", "err", err)
+		}
+		return today
+	}
+	if err := json.Unmarshal(data, &today); err != nil {
+		logger.Error("Failed to parse daily usage", "err", err)
+		return DailyUsage{}
+	}
+	return today
+}
+
+// saveToday writes the daily totals to disk atomically
+// The totals are read under the file lock, so a slower writer never saves older ones last
+func (st *Stats) saveToday() error {
+	dailyUsageFileMu.Lock()
+	defer dailyUsageFileMu.Unlock()
+
+	st.mu.Lock()
+	today := st.today
+	st.mu.Unlock()
+
+	if err := os.MkdirAll(config.DefaultConfigDir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(today)
+	if err != nil {
+		return err
+	}
+	tmp := dailyUsagePath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dailyUsagePath())
+}
@@ -0,0 +1,49 @@
+package monitor
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/llm"
+)
+
+func TestDailyUsageSurvivesRestart(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	price := config.ModelPrice{PromptPer1K: 1, CompletionPer1K: 2}
+
+	st := NewStats()
+	st.Record("m@e", "s1", llm.Usage{PromptTokens: 1000, CompletionTokens: 500}, price, time.Millisecond, nil)
+	if !st.BudgetExceeded(2) {
+		t.Fatal("budget of 2 not exceeded after spending 2")
+	}
+
+	restarted := NewStats()
+	today := restarted.Snapshot(0).Today
+	if today.Requests != 1 || today.PromptTokens != 1000 || today.Cost != 2 {
+		t.Fatalf("today after restart = %+v", today)
+	}
+	if !restarted.BudgetExceeded(2) {
+		t.Fatal("restart reset the daily budget")
+	}
+}
+
+func TestDailyUsageFromAnotherDayIsReset(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := os.MkdirAll(config.DefaultConfigDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	stale := `{"date":"2000-01-01","requests":9,"cost":50}`
+	if err := os.WriteFile(dailyUsagePath(), []byte(stale), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	st := NewStats()
+	if st.BudgetExceeded(1) {
+		t.Fatal("yesterday's spend counted against today's budget")
+	}
+	if today := st.Snapshot(0).Today; today.Requests != 0 || today.Date != time.Now().Format("2006-01-02") {
+		t.Fatalf("today = %+v", today)
+	}
+}
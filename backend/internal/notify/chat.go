@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"winterm-bridge/internal/config"
+)
+
+// ChatNotifier posts to Slack/Discord/Feishu/DingTalk/WeCom-style incoming webhooks
+type ChatNotifier struct {
+	cfg    config.NotifyChannelConfig
+	kind   string
+	client *http.Client
+}
+
+func (c *ChatNotifier) Name() string { return c.cfg.Name }
+
+func (c *ChatNotifier) Send(ctx context.Context, n Notification) error {
+	text := n.Subject() + "\n" + n.Text()
+	endpoint := c.cfg.URL
+
+	var payload any
+	switch c.kind {
+	case TypeSlack:
+		payload = map[string]any{"text": text}
+	case TypeDiscord:
+		payload = map[string]any{"content": text}
+	case TypeFeishu:
+		p := map[string]any{
+			"msg_type": "text",
+			"content":  map[string]string{"text": text},
+		}
+		if c.cfg.Secret != "" {
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			p["timestamp"] = ts
+			p["sign"] = feishuSign(c.cfg.Secret, ts)
+		}
+		payload = p
+	case TypeDingTalk:
+		payload = map[string]any{
+			"msgtype": "text",
+			"text":    map[string]string{"content": text},
+		}
+		if c.cfg.Secret != "" {
+			signed, err := dingTalkSignURL(endpoint, c.cfg.Secret, time.Now())
+			if err != nil {
+				return err
+			}
+			endpoint = signed
+		}
+	case TypeWeCom:
+		payload = map[string]any{
+			"msgtype": "text",
+			"text":    map[string]string{"content": text},
+		}
+	default:
+		return fmt.Errorf("unsupported chat type %q", c.kind)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+	return checkChatResponse(c.kind, respBody)
+}
+
+// checkChatResponse detects errors reported in a 200 response body
+// Feishu, DingTalk and WeCom return HTTP 200 with a non-zero code on failure
+func checkChatResponse(kind string, body []byte) error {
+	var r struct {
+		Code    *int   `json:"code"`
+		Msg     string `json:"msg"`
+		ErrCode *int   `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if len(body) == 0 || json.Unmarshal(body, &r) != nil {
+		return nil
+	}
+	switch kind {
+	case TypeFeishu:
+		if r.Code != nil && *r.Code != 0 {
+			return fmt.Errorf("feishu error %d: %s", *r.Code, r.Msg)
+		}
+	case TypeDingTalk, TypeWeCom:
+		if r.ErrCode != nil && *r.ErrCode != 0 {
+			return fmt.Errorf("%s error %d: %s", kind, *r.ErrCode, r.ErrMsg)
+		}
+	}
+	return nil
+}
+
+// feishuSign computes the Feishu custom bot signature
+// The HMAC key is "timestamp\nsecret" over an empty message
+func feishuSign(secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// dingTalkSignURL appends the DingTalk timestamp and sign query parameters
+func dingTalkSignURL(endpoint, secret string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	ts := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "\n" + secret))
+
+	q := u.Query()
+	q.Set("timestamp", ts)
+	q.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"winterm-bridge/internal/config"
+)
+
+// CommandNotifier runs a local command for each notification
+// The notification is passed as JSON on stdin and as WINTERM_* environment variables
+type CommandNotifier struct {
+	cfg     config.NotifyChannelConfig
+	timeout time.Duration
+}
+
+func (c *CommandNotifier) Name() string { return c.cfg.Name }
+
+func (c *CommandNotifier) Send(ctx context.Context, n Notification) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, c.cfg.Command, c.cfg.Args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"WINTERM_SESSION_ID="+n.SessionID,
+		"WINTERM_SESSION_TITLE="+n.SessionTitle,
+		"WINTERM_TAG="+n.Tag,
+		"WINTERM_DESCRIPTION="+n.Description,
+		"WINTERM_SUBJECT="+n.Subject(),
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		out := strings.TrimSpace(string(output))
+		if len(out) > 512 {
+			out = out[:512]
+		}
+		if out != "" {
+			return fmt.Errorf("command failed: %w: %s", err, out)
+		}
+		return fmt.Errorf("command failed: %w", err)
+	}
+	return nil
+}
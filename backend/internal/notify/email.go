@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"context"
+
+	"winterm-bridge/internal/email"
+)
+
+// EmailNotifier adapts email.Sender to the Notifier interface
+type EmailNotifier struct {
+	sender *email.Sender
+}
+
+// NewEmailNotifier wraps an email sender as the built-in "email" channel
+func NewEmailNotifier(sender *email.Sender) *EmailNotifier {
+	return &EmailNotifier{sender: sender}
+}
+
+func (e *EmailNotifier) Name() string { return EmailChannel }
+
+func (e *EmailNotifier) Send(ctx context.Context, n Notification) error {
+	return e.sender.SendNotification(email.Notification{
+		SessionID:    n.SessionID,
+		SessionTitle: n.SessionTitle,
+		Tag:          n.Tag,
+		Description:  n.Description,
+		Excerpt:      n.Excerpt,
+		Actions:      n.Actions,
+		Time:         n.Time,
+	})
+}
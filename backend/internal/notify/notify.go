@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"winterm-bridge/internal/actions"
+	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/metrics"
+)
+
+// Channel types
+const (
+	TypeWebhook  = "webhook"
+	TypeSlack    = "slack"
+	TypeDiscord  = "discord"
+	TypeFeishu   = "feishu"
+	TypeDingTalk = "dingtalk"
+	TypeWeCom    = "wecom"
+	TypeNtfy     = "ntfy"
+	TypeGotify   = "gotify"
+	TypeCommand  = "command"
+)
+
+// EmailChannel is the name of the built-in email channel
+const EmailChannel = "email"
+
+// defaultTimeout bounds each channel delivery
+const defaultTimeout = 10 * time.Second
+
+// Notification describes a session state change worth telling someone about
+type Notification struct {
+	SessionID    string    `json:"session_id"`
+	SessionTitle string    `json:"session_title"`
+	Tag          string    `json:"tag"`
+	Description  string    `json:"description"`
+	Time         time.Time `json:"time"`
+	Excerpt      string    `json:"-"` // Redacted terminal lines with ANSI escapes, used by rich channels
+	// Actions are one-click replies for sessions waiting on input; POST a token to /api/actions/{token}
+	Actions []actions.Action `json:"actions,omitempty"`
+}
+
+// Subject returns a one-line summary of the notification
+func (n Notification) Subject() string {
+	return fmt.Sprintf("[WinTerm] %s - %s", n.SessionTitle, n.Tag)
+}
+
+// Text returns a short plain-text body, listing reply links when there are any
+func (n Notification) Text() string {
+	text := fmt.Sprintf("会话: %s\n状态: %s\n描述: %s", n.SessionTitle, n.Tag, n.Description)
+	for _, a := range n.Actions {
+		if a.URL != "" {
+			text += fmt.Sprintf("\n回复 %s: %s", a.Label, a.URL)
+		}
+	}
+	return text
+}
+
+// Notifier delivers notifications through one channel
+type Notifier interface {
+	// Name returns the configured channel name
+	Name() string
+	// Send delivers a notification
+	Send(ctx context.Context, n Notification) error
+}
+
+// Build creates a notifier from a channel configuration
+func Build(cfg config.NotifyChannelConfig) (Notifier, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("channel name is required")
+	}
+	if cfg.Name == EmailChannel || cfg.Name == WebPushChannel {
+		return nil, fmt.Errorf("channel name %q is reserved", cfg.Name)
+	}
+
+	timeout := defaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	switch strings.ToLower(cfg.Type) {
+	case TypeWebhook:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("channel %q: url is required", cfg.Name)
+		}
+		return &WebhookNotifier{cfg: cfg, client: client}, nil
+	case TypeSlack, TypeDiscord, TypeFeishu, TypeDingTalk, TypeWeCom:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("channel %q: url is required", cfg.Name)
+		}
+		return &ChatNotifier{cfg: cfg, kind: strings.ToLower(cfg.Type), client: client}, nil
+	case TypeNtfy:
+		if cfg.URL == "" || cfg.Topic == "" {
+			return nil, fmt.Errorf("channel %q: url and topic are required", cfg.Name)
+		}
+		return &NtfyNotifier{cfg: cfg, client: client}, nil
+	case TypeGotify:
+		if cfg.URL == "" || cfg.Token == "" {
+			return nil, fmt.Errorf("channel %q: url and token are required", cfg.Name)
+		}
+		return &GotifyNotifier{cfg: cfg, client: client}, nil
+	case TypeCommand:
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("channel %q: command is required", cfg.Name)
+		}
+		return &CommandNotifier{cfg: cfg, timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("channel %q: unknown type %q", cfg.Name, cfg.Type)
+	}
+}
+
+// Result is the outcome of delivering to one channel
+type Result struct {
+	Channel string `json:"channel"`
+	Error   string `json:"error,omitempty"`
+}
+
+// SendAll delivers a notification to all notifiers in parallel
+// Returns one result per notifier, in the same order
+func SendAll(ctx context.Context, notifiers []Notifier, n Notification) []Result {
+	results := make([]Result, len(notifiers))
+	var wg sync.WaitGroup
+	for i, nt := range notifiers {
+		wg.Add(1)
+		go func(i int, nt Notifier) {
+			defer wg.Done()
+			results[i] = Result{Channel: nt.Name()}
+			if err := nt.Send(ctx, n); err != nil {
+				results[i].Error = err.Error()
+				metrics.Notifications.With(nt.Name(), "failed").Inc()
+				return
+			}
+			metrics.Notifications.With(nt.Name(), "sent").Inc()
+		}(i, nt)
+	}
+	wg.Wait()
+	return results
+}
+
+// TestNotification returns a sample notification for channel tests
+func TestNotification() Notification {
+	return Notification{
+		SessionID:    "00000000-0000-0000-0000-000000000000",
+		SessionTitle: "test",
+		Tag:          "完毕",
+		Description:  "这是一条测试通知",
+		Time:         time.Now(),
+	}
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"winterm-bridge/internal/config"
+)
+
+// NtfyNotifier publishes to an ntfy topic
+type NtfyNotifier struct {
+	cfg    config.NotifyChannelConfig
+	client *http.Client
+}
+
+func (p *NtfyNotifier) Name() string { return p.cfg.Name }
+
+func (p *NtfyNotifier) Send(ctx context.Context, n Notification) error {
+	// Anyone who knows a topic can read it, and reply links type into a shell
+	if !p.cfg.Actions {
+		n.Actions = nil
+	}
+	endpoint := strings.TrimSuffix(p.cfg.URL, "/") + "/" + url.PathEscape(p.cfg.Topic)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(n.Text()))
+	if err != nil {
+		return err
+	}
+	// ntfy headers must be ASCII-safe; RFC 2047 encoding is accepted for the title
+	req.Header.Set("Title", encodeHeader(n.Subject()))
+	req.Header.Set("Tags", "computer")
+	if p.cfg.Priority > 0 {
+		req.Header.Set("Priority", strconv.Itoa(p.cfg.Priority))
+	}
+	if p.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.cfg.Token)
+	}
+	if actions := ntfyActions(n); actions != "" {
+		req.Header.Set("Actions", actions)
+	}
+
+	return doRequest(p.client, req)
+}
+
+// ntfyActions renders reply links as ntfy HTTP action buttons (ntfy shows at most three)
+func ntfyActions(n Notification) string {
+	var buttons []string
+	for _, a := range n.Actions {
+		if a.URL == "" || len(buttons) == 3 {
+			continue
+		}
+		buttons = append(buttons, "http, "+a.Label+", "+a.URL+", method=POST, clear=true")
+	}
+	return strings.Join(buttons, "; ")
+}
+
+// GotifyNotifier sends a message to a Gotify server
+type GotifyNotifier struct {
+	cfg    config.NotifyChannelConfig
+	client *http.Client
+}
+
+func (p *GotifyNotifier) Name() string { return p.cfg.Name }
+
+func (p *GotifyNotifier) Send(ctx context.Context, n Notification) error {
+	priority := p.cfg.Priority
+	if priority == 0 {
+		priority = 5
+	}
+	body, err := json.Marshal(map[string]any{
+		"title":    n.Subject(),
+		"message":  n.Text(),
+		"priority": priority,
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimSuffix(p.cfg.URL, "/") + "/message"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", p.cfg.Token)
+
+	return doRequest(p.client, req)
+}
+
+// encodeHeader RFC 2047-encodes non-ASCII header values (ASCII is returned unchanged)
+func encodeHeader(s string) string {
+	return mime.BEncoding.Encode("UTF-8", s)
+}
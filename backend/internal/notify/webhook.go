@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"winterm-bridge/internal/config"
+)
+
+// Webhook signature headers
+const (
+	SignatureHeader = "X-WinTerm-Signature" // "sha256=<hex hmac of timestamp.body>"
+	TimestampHeader = "X-WinTerm-Timestamp" // Unix seconds, included in the signature to prevent replay
+)
+
+// WebhookNotifier POSTs the notification as JSON to a generic endpoint
+type WebhookNotifier struct {
+	cfg    config.NotifyChannelConfig
+	client *http.Client
+}
+
+// webhookPayload is the JSON body sent to generic webhooks
+type webhookPayload struct {
+	Event string `json:"event"`
+	Notification
+}
+
+func (w *WebhookNotifier) Name() string { return w.cfg.Name }
+
+func (w *WebhookNotifier) Send(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(webhookPayload{Event: "session_notification", Notification: n})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if w.cfg.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, ts)
+		req.Header.Set(SignatureHeader, "sha256="+Sign(w.cfg.Secret, ts, body))
+	}
+
+	return doRequest(w.client, req)
+}
+
+// Sign computes the webhook signature over "timestamp.body"
+// Receivers should recompute it with the shared secret and compare in constant time
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// doRequest executes an HTTP request and treats non-2xx responses as errors
+func doRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,320 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/logging"
+)
+
+var logger = logging.For("notify")
+
+// WebPushChannel is the name of the built-in browser push channel
+const WebPushChannel = "webpush"
+
+// Web push parameters
+const (
+	defaultVAPIDSubject = "https://github.com/Cucgua/winterm-bridge"
+	pushTTL             = 12 * time.Hour
+	pushRecordSize      = 4096
+	maxPushBody         = 2048 // Keeps the encrypted payload well under the 4 KB limit
+)
+
+// errSubscriptionGone marks subscriptions the push service no longer accepts
+var errSubscriptionGone = errors.New("subscription expired")
+
+// PushPayload is the JSON delivered to the service worker
+type PushPayload struct {
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	SessionID string `json:"session_id"`
+	Tag       string `json:"tag"`
+	URL       string `json:"url"` // Path the notification click opens
+}
+
+// WebPushNotifier sends VAPID-authenticated, aes128gcm-encrypted pushes to every browser subscription
+type WebPushNotifier struct {
+	key       *ecdsa.PrivateKey
+	publicKey string // base64url uncompressed point, also sent in the Authorization header
+	subject   string
+	client    *http.Client
+}
+
+// NewWebPushNotifier creates the push channel from the stored VAPID keypair
+func NewWebPushNotifier(cfg *config.WebPushConfig) (*WebPushNotifier, error) {
+	key, err := parseVAPIDKey(cfg.VAPIDPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	subject := cfg.Subject
+	if subject == "" {
+		subject = defaultVAPIDSubject
+	}
+	return &WebPushNotifier{
+		key:       key,
+		publicKey: cfg.VAPIDPublicKey,
+		subject:   subject,
+		client:    &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+func (p *WebPushNotifier) Name() string { return WebPushChannel }
+
+// Send pushes to all subscriptions; expired subscriptions are removed
+// Succeeds if at least one subscription accepted the push
+func (p *WebPushNotifier) Send(ctx context.Context, n Notification) error {
+	cfg := config.GetWebPushConfig()
+	if cfg == nil || len(cfg.Subscriptions) == 0 {
+		return fmt.Errorf("no push subscriptions")
+	}
+
+	body := n.Description
+	if len(body) > maxPushBody {
+		body = body[:maxPushBody]
+	}
+	payload, err := json.Marshal(PushPayload{
+		Title:     n.Subject(),
+		Body:      body,
+		SessionID: n.SessionID,
+		Tag:       n.Tag,
+		URL:       "/?session=" + url.QueryEscape(n.SessionID),
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	delivered := 0
+	for _, sub := range cfg.Subscriptions {
+		err := p.SendTo(ctx, sub, payload)
+		switch {
+		case err == nil:
+			delivered++
+		case errors.Is(err, errSubscriptionGone):
+			logger.Info("Removing expired push subscription", "host", endpointHost(sub.Endpoint))
+			if rmErr := config.RemovePushSubscription(sub.Endpoint); rmErr != nil {
+				logger.Error("Failed to remove push subscription", "err", rmErr)
+			}
+		default:
+			errs = append(errs, endpointHost(sub.Endpoint)+": "+err.Error())
+		}
+	}
+	if delivered == 0 {
+		if len(errs) == 0 {
+			return fmt.Errorf("no active push subscriptions")
+		}
+		return fmt.Errorf("push failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// SendTo encrypts a payload for one subscription and posts it to the push service
+func (p *WebPushNotifier) SendTo(ctx context.Context, sub config.PushSubscription, payload []byte) error {
+	body, err := encryptPayload(sub, payload)
+	if err != nil {
+		return err
+	}
+
+	authHeader, err := p.vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(pushTTL.Seconds())))
+	req.Header.Set("Urgency", "high")
+	req.Header.Set("Authorization", authHeader)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return errSubscriptionGone
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// vapidAuthorization builds the RFC 8292 "vapid" Authorization header for an endpoint
+func (p *WebPushNotifier) vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid endpoint")
+	}
+
+	b64 := base64.RawURLEncoding
+	header := b64.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(pushTTL).Unix(),
+		"sub": p.subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + b64.EncodeToString(claims)
+
+	hash := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, p.key, hash[:])
+	if err != nil {
+		return "", err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	return fmt.Sprintf("vapid t=%s.%s, k=%s", unsigned, b64.EncodeToString(sig), p.publicKey), nil
+}
+
+// encryptPayload encrypts a push message per RFC 8291 using the aes128gcm content coding (RFC 8188)
+func encryptPayload(sub config.PushSubscription, plaintext []byte) ([]byte, error) {
+	uaPublicBytes, err := decodeBase64(sub.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh: %w", err)
+	}
+	authSecret, err := decodeBase64(sub.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth: %w", err)
+	}
+
+	curve := ecdh.P256()
+	uaPublic, err := curve.NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh: %w", err)
+	}
+	asPrivate, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	// IKM = HKDF(auth_secret, ecdh_secret, "WebPush: info" || 0x00 || ua_public || as_public, 32)
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublicBytes...)
+	keyInfo = append(keyInfo, asPublicBytes...)
+	ikm := hkdf(authSecret, ecdhSecret, keyInfo, 32)
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Single record: plaintext followed by the 0x02 last-record delimiter, no padding
+	record := append(append([]byte{}, plaintext...), 0x02)
+	if len(record)+gcm.Overhead() > pushRecordSize {
+		return nil, fmt.Errorf("payload too large")
+	}
+
+	// Header: salt (16) || record size (4) || key id length (1) || key id (as_public)
+	out := make([]byte, 0, 21+len(asPublicBytes)+len(record)+gcm.Overhead())
+	out = append(out, salt...)
+	out = binary.BigEndian.AppendUint32(out, pushRecordSize)
+	out = append(out, byte(len(asPublicBytes)))
+	out = append(out, asPublicBytes...)
+	return gcm.Seal(out, nonce, record, nil), nil
+}
+
+// hkdf derives length bytes with HKDF-SHA256; length must be at most 32 (one expand block)
+func hkdf(salt, ikm, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(ikm)
+	prk := extract.Sum(nil)
+
+	expand := hmac.New(sha256.New, prk)
+	expand.Write(info)
+	expand.Write([]byte{0x01})
+	return expand.Sum(nil)[:length]
+}
+
+// GenerateVAPIDKeys creates a new P-256 keypair encoded as base64url (public point, private scalar)
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	b64 := base64.RawURLEncoding
+	return b64.EncodeToString(key.PublicKey().Bytes()), b64.EncodeToString(key.Bytes()), nil
+}
+
+// parseVAPIDKey converts a base64url private scalar into an ECDSA signing key
+func parseVAPIDKey(encoded string) (*ecdsa.PrivateKey, error) {
+	d, err := decodeBase64(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	point := key.PublicKey().Bytes() // 0x04 || X || Y
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(point[1:33]),
+			Y:     new(big.Int).SetBytes(point[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}, nil
+}
+
+// decodeBase64 accepts base64url or standard base64, padded or not, as browsers vary
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(s)
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// endpointHost returns the push service host for logging without the subscription token
+func endpointHost(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "unknown"
+}
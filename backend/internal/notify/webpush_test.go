@@ -0,0 +1,284 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"winterm-bridge/internal/config"
+)
+
+// fakePushService records pushes; endpoints under /gone/ answer 410 and /missing/ 404
+type fakePushService struct {
+	*httptest.Server
+	mu     sync.Mutex
+	pushes []*http.Request
+	bodies [][]byte
+}
+
+func newFakePushService(t *testing.T) *fakePushService {
+	f := &fakePushService{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		f.pushes = append(f.pushes, r)
+		f.bodies = append(f.bodies, body)
+		f.mu.Unlock()
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/gone/"):
+			w.WriteHeader(http.StatusGone)
+		case strings.HasPrefix(r.URL.Path, "/missing/"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// recorded returns the pushes received so far and their bodies
+func (f *fakePushService) recorded() ([]*http.Request, [][]byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.pushes), slices.Clone(f.bodies)
+}
+
+// subscriber is a browser's side of a push subscription
+type subscriber struct {
+	key  *ecdh.PrivateKey
+	auth []byte
+}
+
+func newSubscriber(t *testing.T) *subscriber {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	return &subscriber{key: key, auth: auth}
+}
+
+func (s *subscriber) subscription(endpoint string) config.PushSubscription {
+	b64 := base64.RawURLEncoding
+	return config.PushSubscription{
+		Endpoint: endpoint,
+		P256dh:   b64.EncodeToString(s.key.PublicKey().Bytes()),
+		Auth:     b64.EncodeToString(s.auth),
+	}
+}
+
+// decrypt reverses the aes128gcm coding the way a browser does (RFC 8291, RFC 8188)
+func (s *subscriber) decrypt(t *testing.T, body []byte) []byte {
+	t.Helper()
+	if len(body) < 21 {
+		t.Fatalf("body too short: %d bytes", len(body))
+	}
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != pushRecordSize {
+		t.Errorf("record size %d, want %d", rs, pushRecordSize)
+	}
+	idLen := int(body[20])
+	asPublicBytes := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		t.Fatalf("key id is not a P-256 point: %v", err)
+	}
+	secret, err := s.key.ECDH(asPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyInfo := append([]byte("WebPush: info\x00"), s.key.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, asPublicBytes...)
+	ikm := hkdf(s.auth, secret, keyInfo, 32)
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	record, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("decrypting: %v", err)
+	}
+	if len(record) == 0 || record[len(record)-1] != 0x02 {
+		t.Fatalf("record lacks the last-record delimiter")
+	}
+	return record[:len(record)-1]
+}
+
+func newTestWebPush(t *testing.T) *WebPushNotifier {
+	pub, priv, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewWebPushNotifier(&config.WebPushConfig{VAPIDPublicKey: pub, VAPIDPrivateKey: priv, Subject: "mailto:ops@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// RFC 5869 test case 1; one expand block is the first 32 bytes of its OKM
+func TestHKDF(t *testing.T) {
+	ikm := bytes.Repeat([]byte{0x0b}, 22)
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	want := "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf"
+	if got := hex.EncodeToString(hkdf(salt, ikm, info, 32)); got != want {
+		t.Fatalf("hkdf = %s, want %s", got, want)
+	}
+}
+
+func TestWebPushEncryptsForSubscriber(t *testing.T) {
+	push := newFakePushService(t)
+	p := newTestWebPush(t)
+	sub := newSubscriber(t)
+	payload := []byte(`{"title":"[WinTerm] build - 完毕"}`)
+
+	if err := p.SendTo(context.Background(), sub.subscription(push.URL+"/push/abc"), payload); err != nil {
+		t.Fatalf("SendTo: %v", err)
+	}
+	pushes, bodies := push.recorded()
+	if len(pushes) != 1 {
+		t.Fatalf("%d pushes, want 1", len(pushes))
+	}
+	req := pushes[0]
+	if got := req.Header.Get("Content-Encoding"); got != "aes128gcm" {
+		t.Errorf("Content-Encoding = %q", got)
+	}
+	if req.Header.Get("TTL") == "" {
+		t.Error("TTL header missing")
+	}
+	if got := sub.decrypt(t, bodies[0]); !bytes.Equal(got, payload) {
+		t.Fatalf("decrypted %q, want %q", got, payload)
+	}
+
+	// Each push uses a fresh salt and ephemeral key
+	if err := p.SendTo(context.Background(), sub.subscription(push.URL+"/push/abc"), payload); err != nil {
+		t.Fatalf("SendTo: %v", err)
+	}
+	_, bodies = push.recorded()
+	if bytes.Equal(bodies[0][:16], bodies[1][:16]) {
+		t.Error("salt reused between pushes")
+	}
+}
+
+func TestWebPushVAPIDToken(t *testing.T) {
+	push := newFakePushService(t)
+	p := newTestWebPush(t)
+	sub := newSubscriber(t)
+
+	if err := p.SendTo(context.Background(), sub.subscription(push.URL+"/push/abc"), []byte("{}")); err != nil {
+		t.Fatalf("SendTo: %v", err)
+	}
+	pushes, _ := push.recorded()
+	auth := pushes[0].Header.Get("Authorization")
+	token, key, ok := strings.Cut(strings.TrimPrefix(auth, "vapid t="), ", k=")
+	if !strings.HasPrefix(auth, "vapid t=") || !ok {
+		t.Fatalf("Authorization = %q, want vapid t=..., k=...", auth)
+	}
+	if key != p.publicKey {
+		t.Errorf("k = %q, want the VAPID public key", key)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("JWT has %d parts", len(parts))
+	}
+	b64 := base64.RawURLEncoding
+	var header struct{ Typ, Alg string }
+	var claims struct {
+		Aud string
+		Exp int64
+		Sub string
+	}
+	headerJSON, _ := b64.DecodeString(parts[0])
+	claimsJSON, _ := b64.DecodeString(parts[1])
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Alg != "ES256" {
+		t.Errorf("header %s: alg must be ES256", headerJSON)
+	}
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		t.Fatalf("claims %s: %v", claimsJSON, err)
+	}
+	if claims.Aud != push.URL {
+		t.Errorf("aud = %q, want %q", claims.Aud, push.URL)
+	}
+	if claims.Sub != "mailto:ops@example.com" {
+		t.Errorf("sub = %q", claims.Sub)
+	}
+	if exp := time.Unix(claims.Exp, 0); exp.Before(time.Now()) || exp.After(time.Now().Add(24*time.Hour)) {
+		t.Errorf("exp %v must be in the future and within 24 hours", exp)
+	}
+
+	// The signature verifies with the public key from k
+	point, err := decodeBase64(key)
+	if err != nil || len(point) != 65 {
+		t.Fatalf("k is not an uncompressed P-256 point")
+	}
+	pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(point[1:33]), Y: new(big.Int).SetBytes(point[33:])}
+	sig, _ := b64.DecodeString(parts[2])
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if len(sig) != 64 || !ecdsa.Verify(pub, hash[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+		t.Fatal("JWT signature does not verify")
+	}
+}
+
+func TestWebPushRemovesGoneSubscriptions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	push := newFakePushService(t)
+	p := newTestWebPush(t)
+
+	live, gone, missing := newSubscriber(t), newSubscriber(t), newSubscriber(t)
+	for _, sub := range []config.PushSubscription{
+		live.subscription(push.URL + "/push/live"),
+		gone.subscription(push.URL + "/gone/1"),
+		missing.subscription(push.URL + "/missing/2"),
+	} {
+		if err := config.SavePushSubscription(sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.Send(context.Background(), TestNotification()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	pushes, bodies := push.recorded()
+	if len(pushes) != 3 {
+		t.Fatalf("%d pushes, want 3", len(pushes))
+	}
+	subs := config.GetWebPushConfig().Subscriptions
+	if len(subs) != 1 || subs[0].Endpoint != push.URL+"/push/live" {
+		t.Fatalf("subscriptions after 404/410: %+v", subs)
+	}
+
+	// The payload the service worker gets names the session
+	var payload PushPayload
+	if err := json.Unmarshal(live.decrypt(t, bodies[0]), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.SessionID != TestNotification().SessionID || payload.Tag != "完毕" {
+		t.Errorf("payload = %+v", payload)
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/gorilla/websocket"
 	"winterm-bridge/internal/auth"
+	"winterm-bridge/internal/events"
+	"winterm-bridge/internal/metrics"
 	"winterm-bridge/internal/session"
 )
 
@@ -36,13 +38,15 @@ type Handler struct {
 	manager    *Manager
 	registry   *session.Registry
 	tokenStore *auth.AttachmentTokenStore
+	events     *events.Hub
 }
 
-func NewHandler(manager *Manager, registry *session.Registry, tokenStore *auth.AttachmentTokenStore) *Handler {
+func NewHandler(manager *Manager, registry *session.Registry, tokenStore *auth.AttachmentTokenStore, hub *events.Hub) *Handler {
 	return &Handler{
 		manager:    manager,
 		registry:   registry,
 		tokenStore: tokenStore,
+		events:     hub,
 	}
 }
 
@@ -73,6 +77,7 @@ func (h *Handler) ServeWS(w http.ResponseWriter, r *http.Request) {
 	// Validate attachment token
 	attachment, valid := h.tokenStore.Validate(token)
 	if !valid {
+		metrics.AuthFailures.With("attachment").Inc()
 		http.Error(w, "invalid or expired token", http.StatusUnauthorized)
 		return
 	}
@@ -90,6 +95,14 @@ func (h *Handler) ServeWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Refuse new terminals once shutdown has begun
+	select {
+	case <-h.manager.Done():
+		http.Error(w, "server restarting", http.StatusServiceUnavailable)
+		return
+	default:
+	}
+
 	// Upgrade to WebSocket
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -105,6 +118,7 @@ func (h *Handler) ServeWS(w http.ResponseWriter, r *http.Request) {
 
 	// Add subscriber
 	sub := inst.AddSubscriber(conn)
+	h.events.Publish(events.TypeClientAttached, sessionID, events.ClientData{Clients: inst.SubscriberCount()})
 
 	// Create write channel for serializing all writes
 	writeCh := make(chan writeRequest, 16)
@@ -117,6 +131,7 @@ func (h *Handler) ServeWS(w http.ResponseWriter, r *http.Request) {
 
 	// Cleanup
 	inst.RemoveSubscriber(conn)
+	h.events.Publish(events.TypeClientDetached, sessionID, events.ClientData{Clients: inst.SubscriberCount()})
 	h.manager.Release(sessionID)
 	conn.Close()
 }
@@ -210,6 +225,18 @@ func (h *Handler) sendLoop(conn *websocket.Conn, sub *Subscriber, inst *Instance
 			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+		case <-h.manager.Done():
+			// Server shutting down: tell the client so it can reconnect to the new process
+			notice := ControlMessage{Type: "server_restarting", Message: "server restarting"}
+			if h.manager.Reconnect() {
+				notice = ControlMessage{Type: "reconnect", Message: "server restarted, reattach with the same token"}
+			}
+			if msg, err := json.Marshal(notice); err == nil {
+				conn.SetWriteDeadline(time.Now().Add(writeWait))
+				conn.WriteMessage(websocket.TextMessage, msg)
+			}
+			closeWithCode(conn, websocket.CloseServiceRestart, "server restarting")
+			return
 		}
 	}
 }
@@ -1,6 +1,7 @@
 package pty
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,6 +11,12 @@ import (
 
 	"github.com/creack/pty"
 	"github.com/gorilla/websocket"
+	"winterm-bridge/internal/metrics"
+)
+
+var (
+	droppedInput  = metrics.DroppedFrames.With("input")
+	droppedOutput = metrics.DroppedFrames.With("output")
 )
 
 type Config struct {
@@ -22,6 +29,11 @@ type Manager struct {
 	instances  map[string]*Instance
 	socketPath string
 	idleTTL    time.Duration
+	exited     map[string]bool // Sessions whose last PTY exited on its own
+
+	shutdownCh   chan struct{} // Closed when the server begins shutting down
+	shutdownOnce sync.Once
+	reconnect    bool // Set before shutdownCh closes: a new process is taking over
 }
 
 type Subscriber struct {
@@ -40,6 +52,7 @@ type Instance struct {
 	LastActive time.Time
 	stopTimer  *time.Timer
 	closed     bool
+	lastInput  time.Time // Last time input was written to the PTY
 
 	subscribers map[*websocket.Conn]*Subscriber
 	subMu       sync.RWMutex
@@ -48,6 +61,9 @@ type Instance struct {
 	doneCh   chan struct{}
 	closeOnce sync.Once
 
+	bytesIn  *metrics.Counter
+	bytesOut *metrics.Counter
+
 	mu sync.Mutex
 }
 
@@ -68,6 +84,8 @@ func NewManager(cfg Config) *Manager {
 		instances:  make(map[string]*Instance),
 		socketPath: socketPath,
 		idleTTL:    idle,
+		exited:     make(map[string]bool),
+		shutdownCh: make(chan struct{}),
 	}
 }
 
@@ -117,6 +135,8 @@ func (m *Manager) EnsureInstance(sessionID, tmuxName string) (*Instance, error)
 		subscribers: make(map[*websocket.Conn]*Subscriber),
 		writeCh:     make(chan []byte, 256),
 		doneCh:      make(chan struct{}),
+		bytesIn:     metrics.SessionBytesIn.With(sessionID),
+		bytesOut:    metrics.SessionBytesOut.With(sessionID),
 	}
 
 	m.mu.Lock()
@@ -140,6 +160,10 @@ func (m *Manager) EnsureInstance(sessionID, tmuxName string) (*Instance, error)
 		existing.mu.Unlock()
 	}
 	m.instances[sessionID] = inst
+	if m.exited[sessionID] {
+		metrics.PTYRestarts.Inc()
+		delete(m.exited, sessionID)
+	}
 	m.mu.Unlock()
 
 	go inst.readLoop(m)
@@ -208,6 +232,76 @@ func (m *Manager) removeInstance(sessionID string) {
 	m.mu.Unlock()
 }
 
+// Done is closed when the server starts shutting down
+func (m *Manager) Done() <-chan struct{} {
+	return m.shutdownCh
+}
+
+// Reconnect reports whether clients should reattach right away after Done closes
+func (m *Manager) Reconnect() bool {
+	<-m.shutdownCh
+	return m.reconnect
+}
+
+// Shutdown tells every client the server is restarting, waits for their sockets to close,
+// then stops all PTY instances. tmux sessions keep running
+// With reconnect, clients are told a new process is already serving and to reattach
+func (m *Manager) Shutdown(ctx context.Context, reconnect bool) {
+	m.shutdownOnce.Do(func() {
+		m.reconnect = reconnect
+		close(m.shutdownCh)
+	})
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+wait:
+	for m.ConnectionCount() > 0 {
+		select {
+		case <-ctx.Done():
+			break wait
+		case <-ticker.C:
+		}
+	}
+
+	m.mu.Lock()
+	insts := m.instances
+	m.instances = make(map[string]*Instance)
+	m.mu.Unlock()
+
+	for _, inst := range insts {
+		inst.mu.Lock()
+		inst.closed = true
+		if inst.stopTimer != nil {
+			inst.stopTimer.Stop()
+			inst.stopTimer = nil
+		}
+		inst.mu.Unlock()
+		inst.close()
+	}
+}
+
+// InstanceCount returns the number of running PTY instances
+func (m *Manager) InstanceCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.instances)
+}
+
+// ConnectionCount returns the number of subscribers across all instances
+func (m *Manager) ConnectionCount() int {
+	m.mu.Lock()
+	insts := make([]*Instance, 0, len(m.instances))
+	for _, inst := range m.instances {
+		insts = append(insts, inst)
+	}
+	m.mu.Unlock()
+	total := 0
+	for _, inst := range insts {
+		total += inst.SubscriberCount()
+	}
+	return total
+}
+
 // Instance methods
 
 func (inst *Instance) close() {
@@ -231,6 +325,12 @@ func (inst *Instance) readLoop(m *Manager) {
 		n, err := inst.Pty.Read(buf)
 		if err != nil {
 			inst.broadcastError("pty process exited")
+			if !inst.IsClosed() {
+				// Not stopped for idleness: the next attach is a restart
+				m.mu.Lock()
+				m.exited[inst.SessionID] = true
+				m.mu.Unlock()
+			}
 			inst.markClosed()
 			m.removeInstance(inst.SessionID)
 			inst.close()
@@ -239,6 +339,7 @@ func (inst *Instance) readLoop(m *Manager) {
 		if n > 0 {
 			data := make([]byte, n)
 			copy(data, buf[:n])
+			inst.bytesOut.Add(uint64(n))
 			inst.broadcast(data)
 		}
 	}
@@ -258,11 +359,17 @@ func (inst *Instance) writeLoop() {
 }
 
 func (inst *Instance) Write(data []byte) {
+	inst.mu.Lock()
+	inst.lastInput = time.Now()
+	inst.mu.Unlock()
+
 	select {
 	case inst.writeCh <- data:
+		inst.bytesIn.Add(uint64(len(data)))
 	case <-inst.doneCh:
 	default:
 		// Drop if buffer full
+		droppedInput.Inc()
 	}
 }
 
@@ -293,6 +400,13 @@ func (inst *Instance) RemoveSubscriber(conn *websocket.Conn) {
 	inst.subMu.Unlock()
 }
 
+// SubscriberCount returns the number of connected subscribers
+func (inst *Instance) SubscriberCount() int {
+	inst.subMu.RLock()
+	defer inst.subMu.RUnlock()
+	return len(inst.subscribers)
+}
+
 func (inst *Instance) broadcast(data []byte) {
 	inst.subMu.RLock()
 	defer inst.subMu.RUnlock()
@@ -307,6 +421,7 @@ func (inst *Instance) broadcast(data []byte) {
 		case sub.SendCh <- data:
 		default:
 			// Drop if buffer full
+			droppedOutput.Inc()
 		}
 	}
 }
@@ -357,6 +472,21 @@ func (m *Manager) BroadcastToSession(sessionID string, data []byte) {
 		select {
 		case sub.SendCh <- data:
 		default:
+			droppedOutput.Inc()
 		}
 	}
 }
+
+// ClientActivity returns the number of attached clients and the time of their last input
+func (m *Manager) ClientActivity(sessionID string) (int, time.Time) {
+	m.mu.Lock()
+	inst, ok := m.instances[sessionID]
+	m.mu.Unlock()
+	if !ok {
+		return 0, time.Time{}
+	}
+	inst.mu.Lock()
+	lastInput := inst.lastInput
+	inst.mu.Unlock()
+	return inst.SubscriberCount(), lastInput
+}
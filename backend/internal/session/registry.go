@@ -3,7 +3,6 @@ package session
 import (
 	"errors"
 	"fmt"
-	"log"
 	"regexp"
 	"sync"
 	"time"
@@ -11,10 +10,15 @@ import (
 	"github.com/gorilla/websocket"
 	"winterm-bridge/internal/auth"
 	"winterm-bridge/internal/config"
+	"winterm-bridge/internal/events"
+	"winterm-bridge/internal/logging"
+	"winterm-bridge/internal/metrics"
 	"winterm-bridge/internal/monitor"
 	"winterm-bridge/internal/tmux"
 )
 
+var logger = logging.For("registry")
+
 var (
 	ErrSessionNotFound = errors.New("session not found")
 	ErrInvalidToken    = errors.New("invalid token")
@@ -22,6 +26,7 @@ var (
 
 type Registry struct {
 	sessions map[string]*Session
+	events   *events.Hub
 	mu       sync.RWMutex
 }
 
@@ -29,6 +34,21 @@ func NewRegistry() *Registry {
 	return &Registry{sessions: make(map[string]*Session)}
 }
 
+// SetEventHub sets the hub that receives session lifecycle events
+func (r *Registry) SetEventHub(hub *events.Hub) {
+	r.mu.Lock()
+	r.events = hub
+	r.mu.Unlock()
+}
+
+// publish sends a session lifecycle event (safe to call with or without r.mu held)
+func (r *Registry) publish(eventType string, s *Session) {
+	r.events.Publish(eventType, s.ID, events.SessionData{
+		Title:    s.Title,
+		TmuxName: s.TmuxName,
+	})
+}
+
 // EnsureDefaultSession creates a default session if no sessions exist
 func (r *Registry) EnsureDefaultSession(title, workingDir string) error {
 	r.mu.RLock()
@@ -52,6 +72,31 @@ func (r *Registry) Get(sessionID string) *Session {
 	return r.sessions[sessionID]
 }
 
+// Rename changes a session's display title, saving it for persistent sessions
+// The tmux name and session ID are unchanged, so open connections and links keep working
+func (r *Registry) Rename(sessionID, title string) error {
+	r.mu.Lock()
+	s, ok := r.sessions[sessionID]
+	if !ok {
+		r.mu.Unlock()
+		return ErrSessionNotFound
+	}
+	s.mu.Lock()
+	s.Title = title
+	persistent := s.IsPersistent
+	s.mu.Unlock()
+	r.events.Publish(events.TypeSessionRenamed, s.ID, events.SessionData{
+		Title:    title,
+		TmuxName: s.TmuxName,
+	})
+	r.mu.Unlock()
+
+	if persistent {
+		return config.RenamePersistentSession(sessionID, title)
+	}
+	return nil
+}
+
 // DiscoverExisting scans for existing tmux sessions and adds them to the registry
 // Also removes sessions whose tmux session no longer exists (unless persistent/ghost)
 func (r *Registry) DiscoverExisting() {
@@ -98,6 +143,7 @@ func (r *Registry) DiscoverExisting() {
 		tmux.EnsureStatusOff(tmuxName)
 
 		r.sessions[id] = s
+		r.publish(events.TypeSessionCreated, s)
 	}
 
 	// Phase 2: Remove sessions whose tmux no longer exists (non-persistent, non-ghost only)
@@ -109,6 +155,7 @@ func (r *Registry) DiscoverExisting() {
 			if !s.IsGhost && s.TmuxName != "" && !tmuxSet[s.TmuxName] {
 				s.IsGhost = true
 				s.State = SessionDetached
+				r.publish(events.TypeSessionGhosted, s)
 			}
 			continue
 		}
@@ -123,6 +170,7 @@ func (r *Registry) DiscoverExisting() {
 	}
 
 	for _, id := range toDelete {
+		r.publish(events.TypeSessionDeleted, r.sessions[id])
 		delete(r.sessions, id)
 	}
 }
@@ -190,6 +238,7 @@ func (r *Registry) CreateWithTitle(token string, title string, workingDir string
 
 	r.mu.Lock()
 	r.sessions[id] = s
+	r.publish(events.TypeSessionCreated, s)
 	r.mu.Unlock()
 	return s, nil
 }
@@ -293,12 +342,12 @@ func (r *Registry) Cleanup(interval time.Duration) {
 		// Only discover new tmux sessions, no auto-deletion
 		r.DiscoverExisting()
 		// Update working directories for persistent sessions
-		r.updatePersistentSessionPaths()
+		r.UpdatePersistentSessionPaths()
 	}
 }
 
-// updatePersistentSessionPaths updates the saved working directory for all persistent sessions
-func (r *Registry) updatePersistentSessionPaths() {
+// UpdatePersistentSessionPaths updates the saved working directory for all persistent sessions
+func (r *Registry) UpdatePersistentSessionPaths() {
 	r.mu.RLock()
 	var toUpdate []struct {
 		id         string
@@ -336,6 +385,7 @@ func (r *Registry) updatePersistentSessionPaths() {
 				ps := config.PersistentSession{
 					ID:         item.id,
 					Title:      item.title,
+					TmuxName:   item.tmuxName,
 					WorkingDir: newPath,
 					CreatedAt:  item.createdAt,
 				}
@@ -357,6 +407,7 @@ func (r *Registry) Delete(sessionID string) error {
 	}
 	// 先从 map 删除，防止新请求访问已删除的 session
 	delete(r.sessions, sessionID)
+	r.publish(events.TypeSessionDeleted, s)
 	r.mu.Unlock() // 立即释放 registry 锁
 
 	// 阶段2: 更新 session 状态并获取 tmux 名称
@@ -380,6 +431,14 @@ func (r *Registry) Delete(sessionID string) error {
 	if isPersistent {
 		_ = config.RemovePersistentSession(sessionID)
 	}
+	// The ID is derived from the tmux name, so a new session with the same name must not inherit
+	// the deleted one's settings
+	_ = config.RemoveSessionMonitorSettings(sessionID)
+	_ = config.RemoveSessionNotifySettings(sessionID)
+
+	// 阶段6: 丢弃该会话的流量指标
+	metrics.SessionBytesIn.Delete(sessionID)
+	metrics.SessionBytesOut.Delete(sessionID)
 
 	return nil
 }
@@ -399,15 +458,20 @@ func (r *Registry) LoadPersistentSessions() {
 		// Check if session already exists in registry
 		if _, exists := r.sessions[ps.ID]; exists {
 			// Already loaded (e.g., from DiscoverExisting), mark as persistent
+			// Discovery titled it after the tmux name; the saved title may be a rename
 			if s := r.sessions[ps.ID]; s != nil {
 				s.IsPersistent = true
 				s.SavedWorkingDir = ps.WorkingDir
+				s.SetTitle(ps.Title)
 			}
 			continue
 		}
 
 		// Check if tmux session exists
-		tmuxName := tmux.SessionPrefix + sanitizeTmuxName(ps.Title)
+		tmuxName := ps.TmuxName
+		if tmuxName == "" {
+			tmuxName = tmux.SessionPrefix + sanitizeTmuxName(ps.Title)
+		}
 		tmuxExists := tmux.SessionExists(tmuxName)
 
 		// Create session entry
@@ -422,12 +486,12 @@ func (r *Registry) LoadPersistentSessions() {
 			s.State = SessionDetached
 			s.IsGhost = false
 			tmux.EnsureStatusOff(tmuxName)
-			log.Printf("[Registry] Loaded persistent session %q with existing tmux", ps.Title)
+			logger.Info("Loaded persistent session with existing tmux", "title", ps.Title)
 		} else {
 			// tmux session doesn't exist, create ghost session
 			s.State = SessionDetached
 			s.IsGhost = true
-			log.Printf("[Registry] Loaded persistent session %q as ghost (tmux not found)", ps.Title)
+			logger.Info("Loaded persistent session as ghost (tmux not found)", "title", ps.Title)
 		}
 
 		r.sessions[ps.ID] = s
@@ -459,6 +523,7 @@ func (r *Registry) PersistSession(sessionID string) error {
 	s.IsPersistent = true
 	s.SavedWorkingDir = workingDir
 	title := s.Title
+	tmuxName := s.TmuxName
 	createdAt := s.CreatedAt
 	s.mu.Unlock()
 
@@ -466,6 +531,7 @@ func (r *Registry) PersistSession(sessionID string) error {
 	ps := config.PersistentSession{
 		ID:         sessionID,
 		Title:      title,
+		TmuxName:   tmuxName,
 		WorkingDir: workingDir,
 		CreatedAt:  createdAt,
 	}
@@ -477,7 +543,7 @@ func (r *Registry) PersistSession(sessionID string) error {
 		return err
 	}
 
-	log.Printf("[Registry] Session %q marked as persistent, workingDir=%s", title, workingDir)
+	logger.Info("Session marked as persistent", "title", title, "dir", workingDir)
 	return nil
 }
 
@@ -510,7 +576,7 @@ func (r *Registry) UnpersistSession(sessionID string) error {
 		return err
 	}
 
-	log.Printf("[Registry] Session %q unmarked from persistent", title)
+	logger.Info("Session unmarked from persistent", "title", title)
 	return nil
 }
 
@@ -546,6 +612,10 @@ func (r *Registry) ReviveGhostSession(sessionID string) error {
 	s.State = SessionDetached
 	s.mu.Unlock()
 
-	log.Printf("[Registry] Revived ghost session %q with tmux %s, workingDir=%s", title, tmuxName, savedDir)
+	r.mu.RLock()
+	r.publish(events.TypeSessionRevived, s)
+	r.mu.RUnlock()
+
+	logger.Info("Revived ghost session", "title", title, "tmux", tmuxName, "dir", savedDir)
 	return nil
 }
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -76,24 +77,11 @@ func (c *Client) SendKeys(data string) error {
 		return fmt.Errorf("client is closed")
 	}
 
-	// Check if data contains any control characters (< 0x20 or 0x7f)
-	hasControl := false
-	for i := 0; i < len(data); i++ {
-		if data[i] < 0x20 || data[i] == 0x7f {
-			hasControl = true
-			break
-		}
-	}
-
 	var cmd string
-	if hasControl {
+	if hasControlChars(data) {
 		// Use send-keys -H to send raw hex bytes
 		// This properly handles control characters like Backspace (0x7f), Ctrl+C (0x03), etc.
-		hexBytes := make([]string, 0, len(data))
-		for i := 0; i < len(data); i++ {
-			hexBytes = append(hexBytes, fmt.Sprintf("%02x", data[i]))
-		}
-		cmd = fmt.Sprintf("send-keys -H %s\n", strings.Join(hexBytes, " "))
+		cmd = fmt.Sprintf("send-keys -H %s\n", strings.Join(hexBytes(data), " "))
 	} else {
 		// For plain text, use -l for literal input
 		cmd = fmt.Sprintf("send-keys -l %q\n", data)
@@ -103,6 +91,25 @@ func (c *Client) SendKeys(data string) error {
 	return err
 }
 
+// hasControlChars reports whether data contains control characters (< 0x20 or 0x7f)
+func hasControlChars(data string) bool {
+	for i := 0; i < len(data); i++ {
+		if data[i] < 0x20 || data[i] == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// hexBytes encodes each byte of data as two hex digits for send-keys -H
+func hexBytes(data string) []string {
+	out := make([]string, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		out = append(out, fmt.Sprintf("%02x", data[i]))
+	}
+	return out
+}
+
 // SendSpecialKey sends special keys (Enter, Tab, etc.) to tmux
 func (c *Client) SendSpecialKey(key string) error {
 	c.mu.Lock()
@@ -410,6 +417,78 @@ func SessionExists(sessionName string) bool {
 	return cmd.Run() == nil
 }
 
+// SendKeysToSession writes raw bytes into a session's active pane, as if typed
+// Uses the same escaping as Client.SendKeys: literal text with -l, control characters as hex with -H
+func SendKeysToSession(sessionName string, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	args := []string{"send-keys", "-t", sessionName}
+	if text := string(data); hasControlChars(text) {
+		args = append(append(args, "-H"), hexBytes(text)...)
+	} else {
+		args = append(args, "-l", "--", text)
+	}
+	return runSendKeys(args)
+}
+
+// SendNamedKeys sends tmux key names (Enter, C-c, Up, ...) to a session's active pane
+func SendNamedKeys(sessionName string, keys ...string) error {
+	for _, k := range keys {
+		if !ValidKeyName(k) {
+			return fmt.Errorf("unknown key %q", k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return runSendKeys(append([]string{"send-keys", "-t", sessionName}, keys...))
+}
+
+// namedKeys are the tmux key names accepted by SendNamedKeys, besides F1-F12 and single characters
+var namedKeys = map[string]bool{
+	"Enter": true, "Tab": true, "BTab": true, "Space": true, "Escape": true, "BSpace": true,
+	"Up": true, "Down": true, "Left": true, "Right": true,
+	"Home": true, "End": true, "PageUp": true, "PageDown": true, "PPage": true, "NPage": true,
+	"Insert": true, "Delete": true, "IC": true, "DC": true,
+}
+
+// ValidKeyName reports whether k is a tmux key name, optionally with C-, M- or S- modifiers
+// Plain words are rejected because tmux would type them literally
+func ValidKeyName(k string) bool {
+	modified := false
+	for len(k) > 2 && (strings.HasPrefix(k, "C-") || strings.HasPrefix(k, "M-") || strings.HasPrefix(k, "S-")) {
+		k = k[2:]
+		modified = true
+	}
+	if namedKeys[k] {
+		return true
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(k, "F")); err == nil && k[0] == 'F' && n >= 1 && n <= 12 {
+		return true
+	}
+	// A single printable character is only a key name with a modifier (C-c, M-x)
+	return modified && len(k) == 1 && k[0] > 0x20 && k[0] < 0x7f
+}
+
+func runSendKeys(args []string) error {
+	if out, err := exec.Command("tmux", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to send keys: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// CaptureSessionHistory captures the pane including up to lines of scrollback, with wrapped lines joined
+// Unlike CaptureSessionPane, blank lines are kept so command output is reproduced faithfully
+func CaptureSessionHistory(sessionName string, lines int) (string, error) {
+	cmd := exec.Command("tmux", "capture-pane", "-p", "-J", "-S", fmt.Sprint(-lines), "-t", sessionName)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to capture pane: %w", err)
+	}
+	return string(output), nil
+}
+
 // CaptureSessionPane captures the visible pane content of a session without needing an active client
 // Returns the plain text content (no escape sequences) with the specified number of non-empty lines
 func CaptureSessionPane(sessionName string, lines int) (string, error) {
@@ -431,6 +510,21 @@ func CaptureSessionPane(sessionName string, lines int) (string, error) {
 	return content, nil
 }
 
+// CaptureSessionPaneANSI captures the last N non-empty lines including color escape sequences
+func CaptureSessionPaneANSI(sessionName string, lines int) (string, error) {
+	cmd := exec.Command("tmux", "capture-pane", "-p", "-e", "-t", sessionName)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to capture pane: %w", err)
+	}
+
+	content := string(output)
+	if lines > 0 {
+		content = getLastNLines(content, lines)
+	}
+	return content, nil
+}
+
 // getLastNLines returns the last N non-empty lines from content
 func getLastNLines(content string, n int) string {
 	allLines := strings.Split(content, "\n")
@@ -0,0 +1,174 @@
+package tmux
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exec defaults
+const (
+	DefaultExecTimeout = 30 * time.Second
+	execPollInterval   = 200 * time.Millisecond
+	execHistoryLines   = 5000 // Scrollback searched for the command's output
+)
+
+// DefaultPromptPattern matches the end of a typical shell prompt
+const DefaultPromptPattern = `[$#%>❯»]\s*$`
+
+// ExecOptions controls how RunCommand detects that a command has finished
+type ExecOptions struct {
+	Timeout time.Duration
+	// Prompt switches from marker detection to waiting for a prompt regex on the last line
+	// Use for shells that are not POSIX-like, or REPLs; no exit status is reported
+	Prompt *regexp.Regexp
+}
+
+// ExecResult is the outcome of RunCommand
+type ExecResult struct {
+	Output   string `json:"output"`
+	ExitCode *int   `json:"exit_code"` // nil when unknown (prompt mode or timeout)
+	TimedOut bool   `json:"timed_out"`
+	Duration int64  `json:"duration_ms"`
+}
+
+// execLocks serializes RunCommand per session so concurrent commands don't interleave
+var execLocks sync.Map
+
+// RunCommand types a command into a session's shell and waits for it to finish
+// By default the command is wrapped in printf markers so its output and exit status can be
+// located in the scrollback; this requires a POSIX-like shell (sh, bash, zsh) at the prompt
+func RunCommand(ctx context.Context, sessionName, command string, opts ExecOptions) (*ExecResult, error) {
+	if strings.ContainsAny(command, "\r\n") {
+		return nil, fmt.Errorf("command must be a single line")
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+
+	lock, _ := execLocks.LoadOrStore(sessionName, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+
+	if opts.Prompt != nil {
+		return runUntilPrompt(ctx, sessionName, command, opts.Prompt, start)
+	}
+
+	nonce := make([]byte, 6)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(nonce)
+	// The echoed command line shows the printf format, not the expanded marker, so only real output matches
+	// The leading space keeps the wrapper out of history with HISTCONTROL=ignorespace
+	line := fmt.Sprintf(" printf '\\n__WB_%%s_BEGIN__\\n' %s; %s; printf '\\n__WB_%%s_END_%%d__\\n' %s $?", id, command, id)
+	begin := "__WB_" + id + "_BEGIN__"
+	end := regexp.MustCompile(`__WB_` + id + `_END_(\d+)__`)
+
+	if err := SendKeysToSession(sessionName, []byte(line)); err != nil {
+		return nil, err
+	}
+	if err := SendNamedKeys(sessionName, "Enter"); err != nil {
+		return nil, err
+	}
+
+	var content string
+	for {
+		var err error
+		content, err = CaptureSessionHistory(sessionName, execHistoryLines)
+		if err != nil {
+			return nil, err
+		}
+		if m := end.FindStringSubmatchIndex(content); m != nil {
+			code, _ := strconv.Atoi(content[m[2]:m[3]])
+			return &ExecResult{
+				Output:   between(content, begin, m[0]),
+				ExitCode: &code,
+				Duration: time.Since(start).Milliseconds(),
+			}, nil
+		}
+		select {
+		case <-ctx.Done():
+			return &ExecResult{
+				Output:   between(content, begin, len(content)),
+				TimedOut: true,
+				Duration: time.Since(start).Milliseconds(),
+			}, nil
+		case <-time.After(execPollInterval):
+		}
+	}
+}
+
+// runUntilPrompt sends a command and waits until a prompt follows the echoed command line
+func runUntilPrompt(ctx context.Context, sessionName, command string, prompt *regexp.Regexp, start time.Time) (*ExecResult, error) {
+	before, err := CaptureSessionHistory(sessionName, execHistoryLines)
+	if err != nil {
+		return nil, err
+	}
+	beforeLines := strings.Split(strings.TrimRight(before, "\n"), "\n")
+	// The pane ends with the prompt, which the echoed command completes
+	echo := beforeLines[len(beforeLines)-1] + command
+
+	if err := SendKeysToSession(sessionName, []byte(command)); err != nil {
+		return nil, err
+	}
+	if err := SendNamedKeys(sessionName, "Enter"); err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for {
+		content, err := CaptureSessionHistory(sessionName, execHistoryLines)
+		if err != nil {
+			return nil, err
+		}
+		lines = strings.Split(strings.TrimRight(content, "\n"), "\n")
+		if i := lastLineWithPrefix(lines, echo); i >= 0 && i < len(lines)-1 && prompt.MatchString(lines[len(lines)-1]) {
+			return &ExecResult{
+				Output:   strings.Join(lines[i+1:len(lines)-1], "\n"),
+				Duration: time.Since(start).Milliseconds(),
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			out := ""
+			if i := lastLineWithPrefix(lines, echo); i >= 0 {
+				out = strings.Join(lines[i+1:], "\n")
+			}
+			return &ExecResult{Output: out, TimedOut: true, Duration: time.Since(start).Milliseconds()}, nil
+		case <-time.After(execPollInterval):
+		}
+	}
+}
+
+// lastLineWithPrefix returns the index of the last line starting with prefix, or -1
+func lastLineWithPrefix(lines []string, prefix string) int {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(lines[i], prefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+// between returns the lines after the last begin marker up to offset stop
+func between(content, begin string, stop int) string {
+	i := strings.LastIndex(content[:stop], begin)
+	if i < 0 {
+		return ""
+	}
+	out := content[i+len(begin) : stop]
+	return strings.TrimRight(strings.TrimPrefix(out, "\n"), "\n")
+}
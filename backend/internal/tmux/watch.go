@@ -0,0 +1,228 @@
+package tmux
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// Watcher capture settings
+const (
+	anchorLines     = 3  // Scrollback lines remembered to notice trimming
+	captureSlack    = 50 // Extra rows captured in case output scrolls the pane between reads
+	captureAttempts = 4  // Reads before accepting one that output raced with
+)
+
+// OutputWatcher reads what a session's pane has printed since a fixed starting line
+//
+// Rows are counted from the top of the scrollback. Lines that have scrolled into the scrollback
+// no longer change, so the watcher keeps their text and each read captures only the rows after
+// them. Once the scrollback reaches history-limit, tmux trims a tenth of it from the top at a
+// time, shifting every row up; the watcher remembers the last scrollback lines it saw and finds
+// where they moved to
+type OutputWatcher struct {
+	sessionName string
+	done        string   // Text of the final lines before next, each ending in a newline
+	next        int      // First row not in done
+	anchor      []string // Last scrollback rows at the last read
+	anchorAt    int      // Row of anchor[0]
+	history     int      // history_size at the last read
+	limit       int      // history_limit at the last read
+}
+
+// panePos is where a pane's cursor and scrollback stand
+type panePos struct {
+	history, cursor, limit, height int
+}
+
+// paneCapture is one read of a pane: its position and its rows from first to the bottom of the
+// visible area, as they are and with wrapped lines joined
+type paneCapture struct {
+	pos    panePos
+	first  int
+	rows   []string
+	joined []string
+}
+
+// NewOutputWatcher starts watching at the cursor line, including lookback earlier lines
+func NewOutputWatcher(sessionName string, lookback int) (*OutputWatcher, error) {
+	c, err := capturePane(sessionName, fmt.Sprint(-anchorLines))
+	if err != nil {
+		return nil, err
+	}
+	w := &OutputWatcher{sessionName: sessionName}
+	w.next = max(c.pos.history+c.pos.cursor-lookback, 0)
+	w.setAnchor(c)
+	return w, nil
+}
+
+// Poll returns the text from the starting line to the bottom of the pane, with wrapped lines
+// joined and trailing blank lines dropped, and the visible pane, used to notice redraws that
+// don't scroll. It runs one tmux command, or a few while output is scrolling the pane fast
+func (w *OutputWatcher) Poll() (output, screen string, err error) {
+	hist, full := w.history, false
+	var c *paneCapture
+	for attempt := 1; ; attempt++ {
+		if c, err = w.capture(hist, full); err != nil {
+			return "", "", err
+		}
+		// Rows that scrolled since hist made the capture start lower than meant
+		stale := c.pos.history != hist
+		hist = c.pos.history
+		shift, found, covered := w.findAnchor(c)
+		if !covered && attempt < captureAttempts {
+			// The anchor is above the captured rows. Retry once with the scrollback size just
+			// read, then from the top: tmux trimmed more than a step, which takes over a tenth of
+			// history-limit printed between polls
+			full = !stale || attempt > 1
+			continue
+		}
+		if !found && covered {
+			// So much was printed that the anchor itself was trimmed, or the scrollback was
+			// cleared: all of it is newer than the last read
+			shift = w.next
+		}
+		w.next = max(w.next-shift, 0)
+		w.anchorAt -= shift
+		if w.next >= c.first || attempt == captureAttempts {
+			break
+		}
+	}
+
+	lines, ends := w.newLines(c)
+	var tail []string
+	for i, line := range lines {
+		if ends[i] < c.pos.history {
+			// Scrolled into the scrollback: final
+			w.done += line + "\n"
+			w.next = ends[i] + 1
+		} else {
+			tail = append(tail, line)
+		}
+	}
+	w.setAnchor(c)
+
+	output = strings.TrimRight(w.done+strings.Join(tail, "\n"), "\n")
+	screen = strings.Join(c.rows[max(len(c.rows)-c.pos.height, 0):], "\n")
+	return output, screen, nil
+}
+
+// capture reads the pane from a little above the anchor, assuming the scrollback holds hist
+// rows; with full, from the top of the scrollback
+func (w *OutputWatcher) capture(hist int, full bool) (*paneCapture, error) {
+	from := "-"
+	if !full {
+		above := captureSlack
+		if step := trimStep(w.limit); hist >= w.limit-step {
+			above += step // A trim moves the anchor up by a step
+		}
+		from = fmt.Sprint(min(w.anchorAt, w.next) - above - hist)
+	}
+	return capturePane(w.sessionName, from)
+}
+
+// findAnchor returns how many rows tmux has trimmed from the top of the scrollback since the
+// last read, by finding where the anchor moved to. tmux trims in steps of a tenth of
+// history-limit, so only those shifts are tried. covered is false if a shift could not be
+// checked because its rows were not captured
+func (w *OutputWatcher) findAnchor(c *paneCapture) (shift int, found, covered bool) {
+	if len(w.anchor) == 0 {
+		return 0, true, true
+	}
+	step := trimStep(c.pos.limit)
+	for shift = 0; w.anchorAt-shift >= 0; shift += step {
+		at := w.anchorAt - shift
+		if at < c.first {
+			return 0, false, false
+		}
+		if at+len(w.anchor) > c.pos.history {
+			continue
+		}
+		if slices.Equal(c.rows[at-c.first:at-c.first+len(w.anchor)], w.anchor) {
+			return shift, true, true
+		}
+	}
+	return 0, false, true
+}
+
+// newLines returns the joined lines that end at row w.next or later and the row each one ends at
+func (w *OutputWatcher) newLines(c *paneCapture) (lines []string, ends []int) {
+	for i, end := range joinedRows(c.joined, c.rows) {
+		if c.first+end >= w.next {
+			lines = append(lines, c.joined[i])
+			ends = append(ends, c.first+end)
+		}
+	}
+	return lines, ends
+}
+
+// setAnchor remembers the last scrollback rows above the first row not yet read
+func (w *OutputWatcher) setAnchor(c *paneCapture) {
+	end := min(w.next, c.pos.history)
+	w.anchorAt = max(end-anchorLines, c.first)
+	w.anchor = nil
+	if end > w.anchorAt {
+		w.anchor = slices.Clone(c.rows[w.anchorAt-c.first : end-c.first])
+	}
+	w.history = c.pos.history
+	w.limit = c.pos.limit
+}
+
+// joinedRows returns, for each joined line, the index of the last of rows it was made from
+// Wrapped rows are full, so a line is matched to rows by counting the characters other than
+// spaces, which capture-pane trims from the end of unjoined rows
+func joinedRows(joined, rows []string) []int {
+	ends := make([]int, len(joined))
+	r := -1
+	for i, line := range joined {
+		want, got := nonSpace(line), 0
+		r++
+		if r < len(rows) {
+			got = nonSpace(rows[r])
+		}
+		for got < want && r+1 < len(rows) {
+			r++
+			got += nonSpace(rows[r])
+		}
+		ends[i] = min(r, len(rows)-1)
+	}
+	return ends
+}
+
+func nonSpace(s string) int {
+	return len(s) - strings.Count(s, " ")
+}
+
+// trimStep is how many rows tmux trims from a full scrollback at a time
+func trimStep(limit int) int {
+	return max(limit/10, 1)
+}
+
+// capturePane reads a pane's position and its rows from line from (counted from the top of the
+// visible area, negative in the scrollback, or "-" for the top of the scrollback) to the bottom,
+// as they are and joined. It is one tmux call, so no output can scroll the pane between the parts
+func capturePane(sessionName, from string) (*paneCapture, error) {
+	out, err := exec.Command("tmux",
+		"display-message", "-p", "-t", sessionName, "#{history_size} #{cursor_y} #{history_limit} #{pane_height}", ";",
+		"capture-pane", "-p", "-S", from, "-t", sessionName, ";",
+		"capture-pane", "-p", "-J", "-S", from, "-t", sessionName).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture pane: %w", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	c := &paneCapture{}
+	if _, err := fmt.Sscan(lines[0], &c.pos.history, &c.pos.cursor, &c.pos.limit, &c.pos.height); err != nil {
+		return nil, fmt.Errorf("unexpected pane position %q", lines[0])
+	}
+	if from != "-" {
+		var rel int
+		fmt.Sscan(from, &rel)
+		c.first = max(c.pos.history+rel, 0)
+	}
+
+	n := min(c.pos.history+c.pos.height-c.first, len(lines)-1)
+	c.rows = lines[1 : 1+n]
+	c.joined = lines[1+n:]
+	return c, nil
+}
@@ -10,7 +10,8 @@ function AutoRedirect() {
   const isMobile = useDeviceType();
 
   useEffect(() => {
-    navigate(isMobile ? '/mobile' : '/desktop', { replace: true });
+    // Keep the query string so deep links (?session=) survive the redirect
+    navigate((isMobile ? '/mobile' : '/desktop') + window.location.search, { replace: true });
   }, [isMobile, navigate]);
 
   return <LoadingScreen />;
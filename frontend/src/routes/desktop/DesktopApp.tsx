@@ -7,6 +7,7 @@ import { socket, ControlMessage } from '../../shared/core/socket';
 import { DesktopLayout } from './DesktopLayout';
 import { useI18n } from '../../shared/i18n';
 import { useAIStore } from '../../shared/stores/aiStore';
+import { takeLinkedSession } from '../../shared/utils/deepLink';
 
 type AuthState = 'loading' | 'awaiting_pin' | 'selecting_session' | 'authenticated';
 
@@ -50,6 +51,14 @@ export default function DesktopApp() {
 
         const { sessions } = await api.listSessions();
         setSessions(sessions);
+
+        // Open the session directly when arriving from a notification link
+        const linked = takeLinkedSession();
+        if (linked && sessions.some((s) => s.id === linked)) {
+          setAuthState('authenticated');
+          await attachToSession(linked);
+          return;
+        }
         setAuthState('selecting_session');
       } catch {
         localStorage.removeItem('winterm_token');
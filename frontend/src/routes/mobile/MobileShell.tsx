@@ -8,6 +8,7 @@ import { useSettingsStore } from '../../shared/stores/settingsStore';
 import { useKeyboardStore } from '../../shared/stores/keyboardStore';
 import { useAIStore } from '../../shared/stores/aiStore';
 import { useI18n } from '../../shared/i18n';
+import { takeLinkedSession } from '../../shared/utils/deepLink';
 import { StatusBar } from './components/StatusBar';
 import { ConnectionStatus } from './components/ConnectionIndicator';
 import { MobileTerminalLayer } from './components/MobileTerminalLayer';
@@ -149,7 +150,15 @@ export default function MobileShell() {
           setSessions([newSession]);
         }
 
-        // Always go to session selection on mobile
+        // Open the session directly when arriving from a notification link
+        const linked = takeLinkedSession();
+        if (linked && sessionList.some((s) => s.id === linked)) {
+          setAuthState('ready');
+          connectToSession(linked);
+          return;
+        }
+
+        // Otherwise go to session selection on mobile
         setAuthState('selecting_session');
       } catch {
         setAuthState('unauthenticated');
@@ -1,6 +1,7 @@
 import React, { useState, useEffect, useCallback } from 'react';
 import { api, AIConfig, EmailConfig } from '../core/api';
 import { useI18n } from '../i18n';
+import { enablePush, isPushSupported } from '../core/push';
 
 interface AISettingsProps {
   isOpen: boolean;
@@ -37,8 +38,10 @@ export const AISettings: React.FC<AISettingsProps> = ({ isOpen, onClose }) => {
   });
   const [isTestingEmail, setIsTestingEmail] = useState(false);
   const [emailTestResult, setEmailTestResult] = useState<{ ok: boolean; error?: string } | null>(null);
+  const [pushResult, setPushResult] = useState<{ ok: boolean; error?: string } | null>(null);
 
   const [isSaving, setIsSaving] = useState(false);
+  const [saveError, setSaveError] = useState<string | null>(null);
   const [isLoading, setIsLoading] = useState(true);
 
   // Load config on mount
@@ -115,9 +118,21 @@ export const AISettings: React.FC<AISettingsProps> = ({ isOpen, onClose }) => {
     }
   };
 
+  // Subscribe this browser to push notifications
+  const handleEnablePush = async () => {
+    setPushResult(null);
+    try {
+      await enablePush();
+      setPushResult({ ok: true });
+    } catch (err) {
+      setPushResult({ ok: false, error: err instanceof Error ? err.message : undefined });
+    }
+  };
+
   // Save config
   const handleSave = async () => {
     setIsSaving(true);
+    setSaveError(null);
     try {
       const [aiResult] = await Promise.all([
         api.setAIConfig(config),
@@ -125,8 +140,8 @@ export const AISettings: React.FC<AISettingsProps> = ({ isOpen, onClose }) => {
       ]);
       setIsRunning(aiResult.running);
       onClose();
-    } catch {
-      // Error handling
+    } catch (err) {
+      setSaveError(err instanceof Error ? err.message : t('save_failed'));
     } finally {
       setIsSaving(false);
     }
@@ -436,12 +451,30 @@ export const AISettings: React.FC<AISettingsProps> = ({ isOpen, onClose }) => {
                   </span>
                 )}
               </div>
+
+              {/* Browser push */}
+              <div className="flex items-center gap-3">
+                <button
+                  onClick={handleEnablePush}
+                  disabled={!isPushSupported()}
+                  className="px-4 py-2 bg-gray-700 hover:bg-gray-600 disabled:bg-gray-800 disabled:text-gray-500 text-white rounded-lg transition-all"
+                >
+                  {t('push_enable')}
+                </button>
+                {!isPushSupported() && <span className="text-sm text-gray-500">{t('push_unsupported')}</span>}
+                {pushResult && (
+                  <span className={`text-sm ${pushResult.ok ? 'text-green-400' : 'text-red-400'}`}>
+                    {pushResult.ok ? t('push_enabled') : pushResult.error || t('push_failed')}
+                  </span>
+                )}
+              </div>
             </>
           )}
         </div>
 
         {/* Footer */}
         <div className="flex items-center justify-end gap-3 px-6 py-4 border-t border-gray-700/50 bg-gray-800/30">
+          {saveError && <span className="mr-auto text-sm text-red-400">{saveError}</span>}
           <button
             onClick={onClose}
             className="px-4 py-2 text-gray-400 hover:text-white hover:bg-gray-700 rounded-lg transition-all"
@@ -45,6 +45,7 @@ export interface AttachResponse {
 
 export interface ApiError {
   error: string;
+  fields?: { field: string; message: string }[]; // Rejected config values
 }
 
 export interface CreateSessionOptions {
@@ -335,6 +336,41 @@ class ApiService {
     return this.handleResponse<{ ok: boolean; error?: string }>(response);
   }
 
+  /**
+   * Get the VAPID public key for browser push subscriptions
+   */
+  async getPushKey(): Promise<{ public_key: string }> {
+    const response = await fetch('/api/push/vapid-key', {
+      method: 'GET',
+      headers: this.getAuthHeaders(),
+    });
+    return this.handleResponse<{ public_key: string }>(response);
+  }
+
+  /**
+   * Register a browser push subscription
+   */
+  async addPushSubscription(subscription: PushSubscriptionJSON): Promise<{ ok: boolean }> {
+    const response = await fetch('/api/push/subscriptions', {
+      method: 'POST',
+      headers: this.getAuthHeaders(true),
+      body: JSON.stringify(subscription),
+    });
+    return this.handleResponse<{ ok: boolean }>(response);
+  }
+
+  /**
+   * Remove a browser push subscription
+   */
+  async removePushSubscription(endpoint: string): Promise<{ ok: boolean }> {
+    const response = await fetch('/api/push/subscriptions', {
+      method: 'DELETE',
+      headers: this.getAuthHeaders(true),
+      body: JSON.stringify({ endpoint }),
+    });
+    return this.handleResponse<{ ok: boolean }>(response);
+  }
+
   /**
    * Get session settings (notify + persist)
    */
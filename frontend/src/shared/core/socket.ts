@@ -1,5 +1,7 @@
+import { api } from './api';
+
 export interface ControlMessage {
-  type: 'resize' | 'ping' | 'pong' | 'error' | 'title' | 'pause' | 'resume' | 'ai_summary';
+  type: 'resize' | 'ping' | 'pong' | 'error' | 'title' | 'pause' | 'resume' | 'ai_summary' | 'server_restarting' | 'reconnect';
   cols?: number;
   rows?: number;
   message?: string;
@@ -28,12 +30,15 @@ export interface ControlMessage {
  *     {"type":"pong"}
  *     {"type":"title","text":"..."}
  *     {"type":"error","message":"..."}
+ *     {"type":"server_restarting","message":"..."}  (followed by close code 1012)
+ *     {"type":"reconnect","message":"..."}          (new process is serving; reattach, then 1012)
  */
 export class SocketService {
   private ws: WebSocket | null = null;
   private reconnectTimer: number | undefined;
   private keepAliveTimer: number | undefined;
   private currentSessionId: string = '';
+  private reattachPending = false;
   private textEncoder = new TextEncoder();
 
   // Flow control state
@@ -99,6 +104,12 @@ export class SocketService {
 
     this.ws.onclose = () => {
       this.stopKeepAlive();
+      if (this.reattachPending) {
+        // Server handed over to a new process: reattach quietly with the same bearer token
+        this.reattachPending = false;
+        this.reattach(sessionId, 0);
+        return;
+      }
       this.onCloseCallbacks.forEach(cb => cb());
     };
 
@@ -124,9 +135,34 @@ export class SocketService {
         // AI session summary update
         this.onControlCallbacks.forEach(cb => cb(msg));
         break;
+      case 'server_restarting':
+        // Server is shutting down; the socket closes next with code 1012
+        this.onControlCallbacks.forEach(cb => cb(msg));
+        break;
+      case 'reconnect':
+        // Server restarted in place; reattach once this socket closes
+        this.reattachPending = true;
+        this.onControlCallbacks.forEach(cb => cb(msg));
+        break;
     }
   }
 
+  private reattach(sessionId: string, attempt: number): void {
+    this.reconnectTimer = window.setTimeout(async () => {
+      if (this.currentSessionId !== sessionId) return;
+      try {
+        const { ws_url } = await api.attachSession(sessionId);
+        this.connectWithToken(ws_url, sessionId);
+      } catch {
+        if (attempt < 4) {
+          this.reattach(sessionId, attempt + 1);
+        } else {
+          this.onCloseCallbacks.forEach(cb => cb());
+        }
+      }
+    }, 200 * 2 ** attempt);
+  }
+
   private startKeepAlive(): void {
     this.stopKeepAlive();
     this.keepAliveTimer = window.setInterval(() => {
@@ -232,6 +268,7 @@ export class SocketService {
 
   disconnect(): void {
     clearTimeout(this.reconnectTimer);
+    this.reattachPending = false;
     this.stopKeepAlive();
     this.ws?.close();
     this.ws = null;
@@ -105,6 +105,11 @@ export const translations = {
     email_test: 'Send Test Email',
     email_test_success: 'Test email sent',
     email_test_failed: 'Failed to send test email',
+    push_enable: 'Enable Browser Notifications',
+    push_enabled: 'Browser notifications enabled on this device',
+    push_unsupported: 'This browser does not support push notifications',
+    push_failed: 'Failed to enable browser notifications',
+    save_failed: 'Failed to save settings',
 
     // Session toggles
     session_notify_on: 'Notification On',
@@ -218,6 +223,11 @@ export const translations = {
     email_test: '发送测试邮件',
     email_test_success: '测试邮件已发送',
     email_test_failed: '测试邮件发送失败',
+    push_enable: '开启浏览器通知',
+    push_enabled: '已在此设备开启浏览器通知',
+    push_unsupported: '此浏览器不支持推送通知',
+    push_failed: '开启浏览器通知失败',
+    save_failed: '保存设置失败',
 
     // Session toggles
     session_notify_on: '通知已开启',
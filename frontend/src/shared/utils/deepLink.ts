@@ -0,0 +1,13 @@
+// Reads the ?session= deep link (used by notification emails) and removes it from the URL
+export function takeLinkedSession(): string | null {
+  const params = new URLSearchParams(window.location.search);
+  const sessionId = params.get('session');
+  if (!sessionId) {
+    return null;
+  }
+
+  params.delete('session');
+  const search = params.toString();
+  window.history.replaceState(null, '', window.location.pathname + (search ? `?${search}` : ''));
+  return sessionId;
+}
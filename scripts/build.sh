@@ -160,6 +160,12 @@ build_single() {
         -o "$output_path/$output_name" \
         ./cmd/server
 
+    # 命令行客户端
+    CGO_ENABLED=0 GOOS="$os" GOARCH="$arch" go build \
+        -ldflags="-s -w" \
+        -o "$output_path/wbctl" \
+        ./cmd/wbctl
+
     # 复制 README 和 LICENSE
     cp "$PROJECT_ROOT/README.md" "$output_path/" 2>/dev/null || true
     cp "$PROJECT_ROOT/LICENSE" "$output_path/" 2>/dev/null || true
@@ -788,8 +788,9 @@ Description=WinTerm Bridge Server
 After=network.target
 
 [Service]
-Type=simple
+Type=notify
 ExecStart=$(which winterm-bridge 2>/dev/null || echo "/usr/local/bin/winterm-bridge")
+ExecReload=/bin/kill -HUP \$MAINPID
 Restart=on-failure
 RestartSec=5
 Environment=HOME=$HOME